@@ -1,77 +1,217 @@
 package zim
 
 import (
-	"errors"
+	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 )
 
-func blobReader(clusterReader io.Reader, offsetSize int64, blobPosition uint32) (
-	reader io.Reader, blobSize int64, err error) {
+// blobError replaces a short read by ErrInvalidBlob, since the blob
+// positions of a valid cluster are always inside of the cluster.
+func blobError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidBlob
+	}
+	return err
+}
 
-	var file, clusterReaderIsFile = clusterReader.(*os.File)
+// blobOffsets returns the start and end positions of the blob in the uncompressed cluster data.
+// The section is the cluster data in the file and the clusterReader returns the uncompressed
+// cluster data, which is the section itself for uncompressed clusters. Only the offsets
+// are read from the clusterReader.
+func blobOffsets(section *io.SectionReader, clusterReader io.Reader, offsetSize int64, blobPosition uint32) (
+	thisBlobPointer, nextBlobPointer int64, err error) {
 
 	var thisBlobIndex = int64(blobPosition) * offsetSize
 
-	// seek to position where we get the relevant start and end positions of the blob
-	if clusterReaderIsFile {
-		var newOffset int64
-		newOffset, err = file.Seek(thisBlobIndex, 1)
-		blobSize = newOffset - thisBlobIndex
-	} else {
-		_, err = io.CopyN(ioutil.Discard, clusterReader, thisBlobIndex)
+	// uncompressed clusters are read at the positions directly
+	if clusterReader == io.Reader(section) {
+		switch offsetSize {
+		case extendedOffsetSize:
+			var this, next uint64
+			if this, err = readUint64At(section, thisBlobIndex); err == nil {
+				next, err = readUint64At(section, thisBlobIndex+offsetSize)
+			}
+			thisBlobPointer, nextBlobPointer = int64(this), int64(next)
+		default:
+			var this, next uint32
+			if this, err = readUint32At(section, thisBlobIndex); err == nil {
+				next, err = readUint32At(section, thisBlobIndex+offsetSize)
+			}
+			thisBlobPointer, nextBlobPointer = int64(this), int64(next)
+		}
+		if err != nil {
+			err = clusterError(section, blobError(err))
+			return
+		}
+		if nextBlobPointer < thisBlobPointer || thisBlobPointer < 0 || nextBlobPointer > section.Size() {
+			err = clusterError(section, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob))
+		}
+		return
 	}
-	if err != nil {
-		err = errors.New("zim: invalid blob position")
+
+	// skip to the position where we get the relevant start and end positions of the blob
+	if _, err = io.CopyN(ioutil.Discard, clusterReader, thisBlobIndex); err != nil {
+		err = clusterError(section, blobError(err))
 		return
 	}
 
 	// read the start and end positions
-	var thisBlobPointer int64
-	var nextBlobPointer int64
 	switch offsetSize {
 	case extendedOffsetSize:
-		thisBlobPointer = int64(readUint64R(clusterReader))
-		nextBlobPointer = int64(readUint64R(clusterReader))
+		var this, next uint64
+		if this, err = readUint64(clusterReader); err == nil {
+			next, err = readUint64(clusterReader)
+		}
+		thisBlobPointer, nextBlobPointer = int64(this), int64(next)
 	default:
-		thisBlobPointer = int64(readUint32R(clusterReader))
-		nextBlobPointer = int64(readUint32R(clusterReader))
+		var this, next uint32
+		if this, err = readUint32(clusterReader); err == nil {
+			next, err = readUint32(clusterReader)
+		}
+		thisBlobPointer, nextBlobPointer = int64(this), int64(next)
 	}
-
-	if nextBlobPointer < thisBlobPointer {
-		err = errors.New("zim: invalid blob index")
+	if err != nil {
+		err = clusterError(section, blobError(err))
 		return
 	}
-
-	// seek to the position of blob data start
-	if clusterReaderIsFile {
-		seek(file, blobSize+thisBlobPointer)
-	} else {
-		var alreadyRead = thisBlobIndex + 2*offsetSize
-		_, err = io.CopyN(ioutil.Discard, clusterReader, thisBlobPointer-alreadyRead)
+	if nextBlobPointer < thisBlobPointer || thisBlobPointer < thisBlobIndex+2*offsetSize {
+		err = clusterError(section, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob))
 	}
+	return
+}
 
+// blobReader returns a reader for the blob data of a cluster, see blobOffsets.
+func blobReader(section *io.SectionReader, clusterReader io.Reader, offsetSize int64, blobPosition uint32) (
+	reader io.Reader, blobSize int64, err error) {
+
+	var thisBlobPointer, nextBlobPointer int64
+	if thisBlobPointer, nextBlobPointer, err = blobOffsets(section, clusterReader, offsetSize, blobPosition); err != nil {
+		return
+	}
 	blobSize = nextBlobPointer - thisBlobPointer
+	if clusterReader == io.Reader(section) {
+		reader = sectionReader(section, thisBlobPointer, blobSize)
+		return
+	}
+
+	// skip to the position of blob data start
+	var alreadyRead = int64(blobPosition)*offsetSize + 2*offsetSize
+	if _, err = io.CopyN(ioutil.Discard, clusterReader, thisBlobPointer-alreadyRead); err != nil {
+		err = clusterError(section, blobError(err))
+		return
+	}
 	reader = io.LimitReader(clusterReader, blobSize)
 	return
 }
 
-// BlobReaderAt returns a LimitedReader for the blob data at the given positions.
+// BlobReaderAt returns a reader for the blob data at the given positions.
+// The reader has its own state and can be used concurrently with other readers.
+// Compressed clusters are taken from the cluster cache, if it is enabled by SetClusterCacheSize.
 func (z *File) BlobReaderAt(clusterPosition, blobPosition uint32) (
 	reader io.Reader, blobSize int64, err error) {
 
-	var clusterInformation uint8
-	reader, clusterInformation, err = z.clusterReader(clusterPosition)
-
-	if err == nil {
-		reader, blobSize, err = blobReader(reader, int64(clusterOffsetSize(clusterInformation)), blobPosition)
+	var section, clusterInformation, sectionErr = z.clusterSection(clusterPosition)
+	if sectionErr != nil {
+		return nil, 0, sectionErr
+	}
+	// the section of the last cluster is estimated up to the checksum, which may exceed the 32MB
+	// ClusterAt accepts; such clusters are read without the cache
+	if clusterCompression(clusterInformation) > 1 && section.Size() <= maxClusterLen {
+		var cluster, cached, cacheErr = z.cachedCluster(clusterPosition)
+		if cacheErr != nil {
+			return nil, 0, cacheErr
+		}
+		if cached {
+			var blob, blobErr = cluster.BlobAt(blobPosition)
+			if blobErr != nil {
+				return nil, 0, clusterError(section, blobErr)
+			}
+			return bytes.NewReader(blob), int64(len(blob)), nil
+		}
+	}
+	if reader, err = decompressor(section, clusterInformation); err == nil {
+		reader, blobSize, err = blobReader(section, reader, int64(clusterOffsetSize(clusterInformation)), blobPosition)
 	}
 	return
 }
 
-// BlobReader returns a LimitedReader for the blob data of the given Directory Entry.
+// BlobReader returns a reader for the blob data of the given Directory Entry.
 func (z *File) BlobReader(e *DirectoryEntry) (
 	reader io.Reader, blobSize int64, err error) {
 	return z.BlobReaderAt(e.ClusterNumber(), e.BlobNumber())
 }
+
+// blobSize returns the size of the blob at the given positions. Only the blob offsets are read,
+// so compressed clusters are decompressed up to the offsets of the blob.
+func (z *File) blobSize(clusterPosition, blobPosition uint32) (int64, error) {
+	var section, clusterInformation, err = z.clusterSection(clusterPosition)
+	if err != nil {
+		return 0, err
+	}
+	var clusterReader io.Reader
+	if clusterReader, err = decompressor(section, clusterInformation); err != nil {
+		return 0, err
+	}
+	var thisBlobPointer, nextBlobPointer, offsetsErr = blobOffsets(section, clusterReader,
+		int64(clusterOffsetSize(clusterInformation)), blobPosition)
+	return nextBlobPointer - thisBlobPointer, offsetsErr
+}
+
+// BlobSeeker reads the data of a blob sequentially or at any offset,
+// so it can be used with http.ServeContent. Size returns the size of the blob.
+type BlobSeeker interface {
+	io.ReadSeeker
+	io.ReaderAt
+	Size() int64
+}
+
+// BlobSeekerAt returns a seekable reader for the blob data at the given positions.
+// Blobs of uncompressed clusters are read at their file positions directly.
+// Blobs of compressed clusters are decompressed into memory once, or taken from the
+// cluster cache, if it is enabled by SetClusterCacheSize; they can't be larger than 32MB.
+// The reader has its own state and can be used concurrently with other readers.
+func (z *File) BlobSeekerAt(clusterPosition, blobPosition uint32) (BlobSeeker, error) {
+	var reader, blobSize, err = z.BlobReaderAt(clusterPosition, blobPosition)
+	if err != nil {
+		return nil, err
+	}
+	// uncompressed and cached blobs are a io.SectionReader or a bytes.Reader
+	if seeker, seekable := reader.(BlobSeeker); seekable {
+		return seeker, nil
+	}
+	if err = z.checkBlobSize(clusterPosition, blobSize); err != nil {
+		return nil, err
+	}
+	var blob = make([]byte, blobSize)
+	if _, err = io.ReadFull(reader, blob); err != nil {
+		return nil, z.clusterErrorAt(clusterPosition, blobError(err))
+	}
+	return bytes.NewReader(blob), nil
+}
+
+// checkBlobSize returns an error, if the blob is too large to be read into memory.
+// Its size is taken from the blob offsets, which may be corrupt; a valid blob fits into a cluster of 32MB.
+func (z *File) checkBlobSize(clusterPosition uint32, blobSize int64) error {
+	if blobSize <= maxClusterLen {
+		return nil
+	}
+	return z.clusterErrorAt(clusterPosition, fmt.Errorf("%w: size exceeds 32MB", ErrInvalidBlob))
+}
+
+// clusterErrorAt wraps err in a ReadError at the file position of the cluster.
+// If the cluster can't be found, err is returned as it is.
+func (z *File) clusterErrorAt(clusterPosition uint32, err error) error {
+	var section, _, sectionErr = z.clusterSection(clusterPosition)
+	if sectionErr != nil {
+		return err
+	}
+	return clusterError(section, err)
+}
+
+// BlobSeeker returns a seekable reader for the blob data of the given Directory Entry,
+// see BlobSeekerAt.
+func (z *File) BlobSeeker(e *DirectoryEntry) (BlobSeeker, error) {
+	return z.BlobSeekerAt(e.ClusterNumber(), e.BlobNumber())
+}
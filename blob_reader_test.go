@@ -0,0 +1,144 @@
+package zim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path"
+	"testing"
+)
+
+func TestBlobSeeker(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	// compressed blobs are read without and with the cluster cache
+	for _, cacheSize := range []int64{0, 1 << 20} {
+		zm.SetClusterCacheSize(cacheSize)
+		for entry, err := range zm.Entries(0) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.IsRedirect() || entry.Namespace() == NamespaceZimMetadata {
+				continue
+			}
+			var reader, _, readerErr = zm.BlobReader(&entry)
+			if readerErr != nil {
+				t.Fatal(readerErr)
+			}
+			var expected, readErr = io.ReadAll(reader)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			var seeker, seekerErr = zm.BlobSeeker(&entry)
+			if seekerErr != nil {
+				t.Fatalf("zm.BlobSeeker(%s) returned `%v`", entry.String(), seekerErr)
+			}
+			if size := seeker.Size(); size != int64(len(expected)) {
+				t.Errorf("size of blob of %s was %d; want %d", entry.String(), size, len(expected))
+			}
+			var middle = int64(len(expected) / 2)
+			var data = make([]byte, 10)
+			if n, err := seeker.ReadAt(data, middle); err != nil && err != io.EOF ||
+				!bytes.Equal(data[:n], expected[middle:middle+int64(n)]) {
+				t.Errorf("seeker.ReadAt(%d) of %s returned `%s`, `%v`", middle, entry.String(), data[:n], err)
+			}
+			if position, err := seeker.Seek(-middle, io.SeekEnd); err != nil || position != int64(len(expected))-middle {
+				t.Errorf("seeker.Seek(%d, io.SeekEnd) of %s returned %d, `%v`", -middle, entry.String(), position, err)
+			}
+			var rest, restErr = io.ReadAll(seeker)
+			if restErr != nil || !bytes.Equal(rest, expected[len(expected)-int(middle):]) {
+				t.Errorf("reading %s after seeking returned `%s`, `%v`", entry.String(), rest, restErr)
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				t.Error(err)
+			}
+			if all, err := io.ReadAll(seeker); err != nil || !bytes.Equal(all, expected) {
+				t.Errorf("reading %s after seeking to the start returned `%v`", entry.String(), err)
+			}
+		}
+	}
+
+	if _, err := zm.BlobSeekerAt(0, 1000); err == nil {
+		t.Error("zm.BlobSeekerAt(0, 1000) returned no error")
+	}
+}
+
+// hugeBlobFile returns a file written by writeTestFile, whose only cluster is replaced by a zstd compressed
+// cluster with an empty blob at each blob position except the given one, which has 33MB.
+// The entries must have compressible contents; front articles are ignored.
+func hugeBlobFile(t *testing.T, entries []writerTestEntry, blobPosition uint32) *File {
+	entries = append([]writerTestEntry(nil), entries...)
+	for i := range entries {
+		entries[i].front = false
+	}
+	var data = writeTestFile(t, WriterOptions{Compression: CompressionZstd}, entries)
+	var clusterPointer = binary.LittleEndian.Uint64(data[binary.LittleEndian.Uint64(data[48:56]):])
+	var checksumPos = binary.LittleEndian.Uint64(data[72:80])
+
+	const blobCount, blobSize = 64, 33 << 20
+	var raw = make([]byte, 4*(blobCount+1), 4*(blobCount+1)+blobSize)
+	for position := uint32(0); position <= blobCount; position++ {
+		var offset = uint32(len(raw))
+		if position > blobPosition {
+			offset += blobSize
+		}
+		binary.LittleEndian.PutUint32(raw[4*position:], offset)
+	}
+	raw = raw[:cap(raw)]
+	var encoder, err = newClusterEncoder(CompressionZstd, 0, 1, len(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoder.close()
+	var cluster, compressErr = encoder.compress(CompressionZstd, uint8(CompressionZstd), raw)
+	if compressErr != nil {
+		t.Fatal(compressErr)
+	}
+
+	var corrupt = append(append(data[:clusterPointer:clusterPointer], cluster...), data[checksumPos:]...)
+	binary.LittleEndian.PutUint64(corrupt[72:80], clusterPointer+uint64(len(cluster)))
+	var zc, openErr = OpenReaderAt(bytes.NewReader(corrupt), int64(len(corrupt)))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if zc.ClusterCount() != 1 {
+		t.Fatalf("file has %d clusters; want 1", zc.ClusterCount())
+	}
+	return zc
+}
+
+func TestBlobSeekerHugeBlob(t *testing.T) {
+	var zc = hugeBlobFile(t, writerTestEntries[:1], 0)
+	// the blob can be streamed, but not read into memory
+	var reader, blobSize, err = zc.BlobReaderAt(0, 0)
+	if err != nil || blobSize != 33<<20 {
+		t.Fatalf("zc.BlobReaderAt(0, 0) returned %d bytes, `%v`", blobSize, err)
+	}
+	if n, err := io.Copy(io.Discard, reader); err != nil || n != blobSize {
+		t.Errorf("reading the blob returned %d bytes, `%v`; want %d bytes", n, err, blobSize)
+	}
+	var readErr *ReadError
+	if _, err = zc.BlobSeekerAt(0, 0); !errors.Is(err, ErrInvalidBlob) || !errors.As(err, &readErr) {
+		t.Errorf("zc.BlobSeekerAt(0, 0) returned `%v`; want a ReadError with `%s`", err, ErrInvalidBlob)
+	}
+	if seeker, err := zc.BlobSeekerAt(0, 1); err != nil || seeker.Size() != 0 {
+		t.Errorf("zc.BlobSeekerAt(0, 1) returned `%v`", err)
+	}
+}
+
+func TestClusterErrorAt(t *testing.T) {
+	var readErr *ReadError
+	if err := z.clusterErrorAt(0, ErrInvalidBlob); !errors.Is(err, ErrInvalidBlob) || !errors.As(err, &readErr) {
+		t.Errorf("z.clusterErrorAt(0) returned `%v`; want a ReadError with `%s`", err, ErrInvalidBlob)
+	}
+	// a missing cluster has no position to report
+	if err := z.clusterErrorAt(z.ClusterCount(), ErrInvalidBlob); err != ErrInvalidBlob {
+		t.Errorf("z.clusterErrorAt(%d) returned `%v`; want `%s`", z.ClusterCount(), err, ErrInvalidBlob)
+	}
+}
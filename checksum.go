@@ -2,18 +2,16 @@ package zim
 
 import (
 	"crypto/md5"
-	"errors"
 	"io"
 )
 
 // InternalChecksum is the MD5 checksum for the ZIM file.
 // It's precalculated and saved in the header.
 func (z *File) InternalChecksum() ([md5.Size]byte, error) {
-	seek(z.f, int64(z.header.checksumPos))
-	var buf, readErr = readSlice(z.f, md5.Size)
+	var buf, readErr = readSliceAt(z.r, int64(z.header.checksumPos), md5.Size)
 	var md5sum [md5.Size]byte
 	if readErr != nil {
-		return md5sum, errors.New("zim: reading internal checksum failed")
+		return md5sum, readError("read internal checksum", int64(z.header.checksumPos), readErr, ErrInvalidHeader)
 	}
 	copy(md5sum[:], buf)
 	return md5sum, nil
@@ -24,8 +22,8 @@ func (z *File) InternalChecksum() ([md5.Size]byte, error) {
 func (z *File) CalculateChecksum() ([md5.Size]byte, error) {
 	var digest = md5.New()
 	var md5Sum [md5.Size]byte
-	seek(z.f, 0)
-	if _, copyErr := io.CopyN(digest, z.f, int64(z.header.checksumPos)); copyErr != nil {
+	var r = io.NewSectionReader(z.r, 0, int64(z.header.checksumPos))
+	if _, copyErr := io.CopyN(digest, r, int64(z.header.checksumPos)); copyErr != nil {
 		return md5Sum, copyErr
 	}
 	copy(md5Sum[:], digest.Sum(nil)[:md5.Size])
@@ -40,7 +38,7 @@ func (z *File) ValidateChecksum() error {
 	} else if calculated, calculatedChecksumErr := z.CalculateChecksum(); calculatedChecksumErr != nil {
 		return calculatedChecksumErr
 	} else if internal != calculated {
-		return errors.New("zim: checksum mismatched")
+		return ErrChecksumMismatch
 	} else {
 		return nil
 	}
@@ -2,9 +2,12 @@ package zim
 
 import (
 	"encoding/binary"
-	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/xi2/xz"
 )
 
 const (
@@ -13,39 +16,118 @@ const (
 	maxClusterLen      = 1024 * 1024 * 32 // 32MB
 )
 
+// Compression is the compression of the data of a cluster, as stored in its cluster information byte.
+type Compression uint8
+
+// Compressions supported by this package.
+const (
+	CompressionNone = Compression(1)
+	CompressionXz   = Compression(4)
+	CompressionZstd = Compression(5)
+)
+
+// extendedCluster is the flag of the cluster information byte for 8 byte blob positions.
+const extendedCluster = 16
+
 func clusterOffsetSize(clusterInformation uint8) uint8 {
-	return 4 << ((clusterInformation & 16) >> 4)
+	return 4 << ((clusterInformation & extendedCluster) >> 4)
 }
 
 func clusterCompression(clusterInformation uint8) uint8 {
 	return clusterInformation & 15
 }
 
-func (z *File) clusterReader(clusterPosition uint32) (reader io.Reader, clusterInformation uint8, err error) {
-	if clusterPosition >= z.ClusterCount() {
-		err = errors.New("zim: invalid cluster position")
+func clusterError(section *io.SectionReader, err error) error {
+	var _, offset, _ = section.Outer()
+	return readError("read cluster", offset-1, err, ErrInvalidCluster)
+}
+
+// clusterSection returns a reader for the cluster data, which starts after the cluster information byte.
+func (z *File) clusterSection(clusterPosition uint32) (section *io.SectionReader, clusterInformation uint8, err error) {
+	var clusterPointer, nextClusterPointer uint64
+	if clusterPointer, err = z.clusterPointerAtPos(clusterPosition); err != nil {
+		return
+	}
+	if nextClusterPointer, err = z.nextClusterPointer(clusterPosition); err != nil {
+		return
+	}
+	if nextClusterPointer <= clusterPointer+1 {
+		err = &ReadError{Op: "read cluster", Offset: int64(clusterPointer), Err: ErrInvalidCluster}
+		return
+	}
+	var informationByte [1]byte
+	if err = readFullAt(z.r, informationByte[:], int64(clusterPointer)); err != nil {
+		err = readError("read cluster", int64(clusterPointer), err, ErrInvalidCluster)
 		return
 	}
-	var clusterPointer = z.clusterPointerAtPos(clusterPosition)
-	seek(z.f, int64(clusterPointer))
-	clusterInformation = readUint8(z.f)
+	clusterInformation = informationByte[0]
+	section = io.NewSectionReader(z.r, int64(clusterPointer)+1, int64(nextClusterPointer-clusterPointer-1))
+	return
+}
+
+// prefetcher is implemented by readers for which one large read
+// is cheaper than many small ones, like the HTTPReaderAt.
+type prefetcher interface {
+	prefetch(off, n int64) error
+}
+
+// decompressor returns a reader for the uncompressed cluster data.
+// Every call creates its own decompressor, so the reader can be used
+// concurrently with other readers of the same File.
+func decompressor(section *io.SectionReader, clusterInformation uint8) (reader io.Reader, err error) {
 	var compression = clusterCompression(clusterInformation)
+	// compressed clusters are always read as a whole
+	if outer, offset, size := section.Outer(); compression > 1 {
+		if p, ok := outer.(prefetcher); ok {
+			if err = p.prefetch(offset, size); err != nil {
+				return nil, clusterError(section, err)
+			}
+		}
+	}
 	switch compression {
 	case 0, 1: // uncompressed
-		reader = z.f
+		reader = section
 	case 4: // xz compressed
-		if err = z.xzReader.Reset(z.f); err == nil {
-			z.xzReader.Multistream(false)
-			reader = z.xzReader
+		var xzReader *xz.Reader
+		if xzReader, err = xz.NewReader(section, 0); err == nil {
+			xzReader.Multistream(false)
+			reader = xzReader
+		}
+	case 5: // zstd compressed
+		var zstdReader *zstd.Decoder
+		if zstdReader, err = zstd.NewReader(section, zstd.WithDecoderConcurrency(1)); err == nil {
+			reader = zstdFrameReader{zstdReader}
 		}
 	default:
 		// 2: zlib compressed (not used anymore)
 		// 3: bzip2 compressed (not used anymore)
-		err = errors.New("zim: unsupported cluster compression")
+		err = ErrUnsupportedCompression
+	}
+	if err != nil {
+		err = clusterError(section, err)
 	}
 	return
 }
 
+// zstdFrameReader ends the stream after the first zstd frame.
+// The length of the last cluster can only be estimated, so the
+// bytes after its frame are not necessarily another frame.
+// The decoder is released as soon as the stream ended.
+type zstdFrameReader struct {
+	d *zstd.Decoder
+}
+
+func (r zstdFrameReader) Read(p []byte) (int, error) {
+	var n, err = r.d.Read(p)
+	if err == zstd.ErrMagicMismatch {
+		err = io.EOF
+	}
+	if err != nil {
+		r.d.Close()
+	}
+	return n, err
+}
+
 func (z *File) lastClusterPosition() uint32 {
 	return z.header.clusterCount - 1
 }
@@ -65,36 +147,37 @@ func (c *Cluster) WasCompressed() bool {
 	return clusterCompression(c.information) > 1
 }
 
-func (z *File) nextClusterPointer(c *Cluster) uint64 {
-	if c.position >= z.lastClusterPosition() {
-		return z.header.checksumPos - 1
+func (z *File) nextClusterPointer(clusterPosition uint32) (uint64, error) {
+	if clusterPosition >= z.lastClusterPosition() {
+		return z.header.checksumPos, nil
 	}
-	return z.clusterPointerAtPos(c.position + 1)
-}
-
-// clusterLen returns the length of the cluster in bytes.
-func (z *File) clusterLen(c *Cluster) int64 {
-	var nextClusterPointer = z.nextClusterPointer(c)
-	var clusterPointer = z.clusterPointerAtPos(c.position)
-	seek(z.f, int64(clusterPointer)+1) // file position was (very likely) changed; seek back.
-	// The +1 is because c.information byte was read afterwards too.
-	return int64(nextClusterPointer - clusterPointer - 1)
+	return z.clusterPointerAtPos(clusterPosition + 1)
 }
 
 // ClusterAt returns the Cluster of the ZIM file at the given cluster position.
 // The complete cluster data is stored uncompressed in memory.
 // If the size of the cluster data is more than 32MB an error is returned
-// and the data is not read into memory.
+// and the data is not read into memory. The data of uncompressed clusters
+// of files opened by OpenMmap is not copied, but references the mapping.
 // Note: Only use this function, when it's needed to read every single blob of a
 // ZIM file into memory (for example when iterating over all contents this improves performance).
 func (z *File) ClusterAt(clusterPosition uint32) (Cluster, error) {
 	var c = Cluster{position: clusterPosition}
-	var clusterLen = z.clusterLen(&c)
-	if clusterLen <= 0 || clusterLen > maxClusterLen {
-		return c, errors.New("zim: invalid cluster size")
-	}
-	var clusterReader, clusterInformation, clusterReaderErr = z.clusterReader(clusterPosition)
+	var section, clusterInformation, sectionErr = z.clusterSection(clusterPosition)
 	c.information = clusterInformation
+	if sectionErr != nil {
+		return c, sectionErr
+	}
+	if section.Size() > maxClusterLen {
+		return c, clusterError(section, fmt.Errorf("%w: size exceeds 32MB", ErrInvalidCluster))
+	}
+	if clusterCompression(clusterInformation) <= 1 {
+		if data, mapped := mappedSection(section, 0, section.Size()); mapped {
+			c.data = data
+			return c, nil
+		}
+	}
+	var clusterReader, clusterReaderErr = decompressor(section, clusterInformation)
 	if clusterReaderErr != nil {
 		return c, clusterReaderErr
 	}
@@ -102,7 +185,7 @@ func (z *File) ClusterAt(clusterPosition uint32) (Cluster, error) {
 	var clusterData, clusterDataErr = ioutil.ReadAll(io.LimitReader(clusterReader, int64(maxClusterLen)))
 
 	if clusterDataErr != nil {
-		return c, clusterDataErr
+		return c, clusterError(section, clusterDataErr)
 	}
 
 	c.data = clusterData
@@ -119,7 +202,7 @@ func (c *Cluster) BlobAt(blobPosition uint32) ([]byte, error) {
 	var thisBlobIndex = uint64(blobPosition) * offsetSize
 	var nextBlobIndex = thisBlobIndex + offsetSize
 	if nextBlobIndex+offsetSize > uint64(len(c.data)) {
-		return nil, errors.New("zim: invalid blob position")
+		return nil, ErrInvalidBlob
 	}
 	var thisBlobPointer uint64
 	var nextBlobPointer uint64
@@ -133,5 +216,5 @@ func (c *Cluster) BlobAt(blobPosition uint32) ([]byte, error) {
 	if nextBlobPointer >= thisBlobPointer && nextBlobPointer <= uint64(len(c.data)) {
 		return c.data[thisBlobPointer:nextBlobPointer], nil
 	}
-	return nil, errors.New("zim: invalid blob index")
+	return nil, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob)
 }
@@ -0,0 +1,185 @@
+package zim
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// ClusterCacheStats reports the usage of the cluster cache of a File.
+type ClusterCacheStats struct {
+	Hits     uint64 // blob reads of compressed clusters which didn't need a decompression
+	Misses   uint64 // decompressed clusters
+	Clusters int    // number of clusters in the cache
+	Bytes    int64  // size of the uncompressed data in the cache
+	MaxBytes int64  // maximum size of the cache; 0 if the cache is disabled
+}
+
+// clusterCache is a LRU cache of decompressed clusters, bounded by the size of their data.
+// Concurrent misses of the same cluster decompress it only once.
+type clusterCache struct {
+	mu       sync.Mutex
+	stats    ClusterCacheStats
+	lru      *list.List               // values are Cluster; front is the most recently used
+	clusters map[uint32]*list.Element // cluster position to element of lru
+	loading  map[uint32]*clusterLoad
+}
+
+// clusterLoad is a decompression of a cluster, which others can wait for.
+type clusterLoad struct {
+	done    chan struct{}
+	cluster Cluster
+	cached  bool
+	err     error
+}
+
+// SetClusterCacheSize enables a cache of decompressed clusters, which is used by BlobReader and BlobReaderAt
+// for compressed clusters. Its size is limited to maxBytes of uncompressed data, the least recently used
+// clusters are removed first; the blobs of larger clusters are read without the cache.
+// A size of 0 disables the cache, which is the default.
+// Loading a page and its images from the same cluster then needs a single decompression.
+func (z *File) SetClusterCacheSize(maxBytes int64) {
+	var c = &z.clusterCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	c.stats.MaxBytes = maxBytes
+	c.evict()
+}
+
+// ClusterCacheStats returns the statistics of the cluster cache.
+func (z *File) ClusterCacheStats() ClusterCacheStats {
+	var c = &z.clusterCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// cachedCluster returns the decompressed cluster at the given position from the cache and
+// adds it on a miss. cached is false if the cache is disabled or the cluster is too large;
+// clusters larger than the cache are not decompressed as a whole, so their blobs are streamed.
+func (z *File) cachedCluster(clusterPosition uint32) (cluster Cluster, cached bool, err error) {
+	var c = &z.clusterCache
+	for sizeChecked := false; ; sizeChecked = true {
+		c.mu.Lock()
+		if c.stats.MaxBytes == 0 {
+			c.mu.Unlock()
+			return
+		}
+		if element, found := c.clusters[clusterPosition]; found {
+			c.stats.Hits++
+			c.lru.MoveToFront(element)
+			c.mu.Unlock()
+			return element.Value.(Cluster), true, nil
+		}
+		if load, loading := c.loading[clusterPosition]; loading {
+			c.stats.Hits++
+			c.mu.Unlock()
+			<-load.done
+			return load.cluster, load.cached, load.err
+		}
+		if sizeChecked {
+			break // c.mu stays locked
+		}
+		// the size is read without holding the lock, so the cluster may be added meanwhile
+		var maxBytes = c.stats.MaxBytes
+		c.mu.Unlock()
+		// errors are reported by the streaming read
+		if size, sizeErr := z.clusterDataSize(clusterPosition); sizeErr != nil || size > maxBytes || size >= maxClusterLen {
+			return
+		}
+	}
+	if c.loading == nil {
+		c.loading = make(map[uint32]*clusterLoad)
+	}
+	var load = &clusterLoad{done: make(chan struct{})}
+	c.loading[clusterPosition] = load
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	load.cluster, load.err = z.ClusterAt(clusterPosition)
+	// ClusterAt truncates clusters at 32MB, their blobs are read without the cache
+	load.cached = load.err == nil && len(load.cluster.data) < maxClusterLen
+
+	c.mu.Lock()
+	delete(c.loading, clusterPosition)
+	if load.cached {
+		c.add(load.cluster)
+	}
+	c.mu.Unlock()
+	close(load.done)
+	return load.cluster, load.cached, load.err
+}
+
+// clusterDataSize returns the size of the uncompressed data of the cluster, which is the last blob offset.
+// Only the blob offsets are read, so compressed clusters are decompressed up to the end of the offsets.
+func (z *File) clusterDataSize(clusterPosition uint32) (int64, error) {
+	var section, clusterInformation, err = z.clusterSection(clusterPosition)
+	if err != nil {
+		return 0, err
+	}
+	var clusterReader io.Reader
+	if clusterReader, err = decompressor(section, clusterInformation); err != nil {
+		return 0, err
+	}
+	var offsetSize = int64(clusterOffsetSize(clusterInformation))
+	var readOffset = func() (int64, error) {
+		if offsetSize == extendedOffsetSize {
+			var offset, err = readUint64(clusterReader)
+			return int64(offset), err
+		}
+		var offset, err = readUint32(clusterReader)
+		return int64(offset), err
+	}
+	// the first offset is the size of the offsets
+	var first, last int64
+	if first, err = readOffset(); err != nil {
+		return 0, clusterError(section, blobError(err))
+	}
+	if first < offsetSize || first%offsetSize != 0 {
+		return 0, clusterError(section, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob))
+	}
+	if first == offsetSize {
+		return first, nil
+	}
+	if _, err = io.CopyN(ioutil.Discard, clusterReader, first-2*offsetSize); err == nil {
+		last, err = readOffset()
+	}
+	if err != nil {
+		return 0, clusterError(section, blobError(err))
+	}
+	return last, nil
+}
+
+// add adds the cluster and removes the least recently used clusters. c.mu must be held.
+func (c *clusterCache) add(cluster Cluster) {
+	if int64(len(cluster.data)) > c.stats.MaxBytes {
+		return
+	}
+	if c.lru == nil {
+		c.lru = list.New()
+		c.clusters = make(map[uint32]*list.Element)
+	}
+	if _, found := c.clusters[cluster.position]; found {
+		return
+	}
+	c.clusters[cluster.position] = c.lru.PushFront(cluster)
+	c.stats.Clusters++
+	c.stats.Bytes += int64(len(cluster.data))
+	c.evict()
+}
+
+// evict removes the least recently used clusters until the cache isn't larger than its maximum size.
+// c.mu must be held.
+func (c *clusterCache) evict() {
+	for c.stats.Bytes > c.stats.MaxBytes && c.lru != nil && c.lru.Len() > 0 {
+		var cluster = c.lru.Remove(c.lru.Back()).(Cluster)
+		delete(c.clusters, cluster.position)
+		c.stats.Clusters--
+		c.stats.Bytes -= int64(len(cluster.data))
+	}
+}
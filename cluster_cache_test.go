@@ -0,0 +1,227 @@
+package zim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestClusterCache(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	var readBlob = func(clusterPosition uint32) []byte {
+		var reader, blobSize, err = zm.BlobReaderAt(clusterPosition, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data, _ = ioutil.ReadAll(reader)
+		if int64(len(data)) != blobSize {
+			t.Errorf("blob of cluster %d has %d bytes; want %d", clusterPosition, len(data), blobSize)
+		}
+		return data
+	}
+	var expectStats = func(hits, misses uint64, clusters int) {
+		t.Helper()
+		var stats = zm.ClusterCacheStats()
+		if stats.Hits != hits || stats.Misses != misses || stats.Clusters != clusters {
+			t.Errorf("zm.ClusterCacheStats() returned %+v; want %d hits, %d misses and %d clusters",
+				stats, hits, misses, clusters)
+		}
+		if stats.Bytes > stats.MaxBytes {
+			t.Errorf("cache holds %d bytes; want at most %d", stats.Bytes, stats.MaxBytes)
+		}
+	}
+
+	// the cache is disabled by default
+	var uncached = [][]byte{readBlob(0), readBlob(1), readBlob(2)}
+	expectStats(0, 0, 0)
+
+	zm.SetClusterCacheSize(1 << 20)
+	for i := 0; i < 3; i++ {
+		for position, expected := range uncached {
+			if !bytes.Equal(readBlob(uint32(position)), expected) {
+				t.Errorf("cached blob of cluster %d differs from the uncached blob", position)
+			}
+		}
+	}
+	// uncompressed clusters are never cached
+	expectStats(4, 2, 2)
+
+	// only one of the compressed clusters fits into the cache
+	var xzCluster, _ = zm.ClusterAt(1)
+	var zstdCluster, _ = zm.ClusterAt(2)
+	var maxBytes = int64(len(xzCluster.data))
+	if int64(len(zstdCluster.data)) > maxBytes {
+		maxBytes = int64(len(zstdCluster.data))
+	}
+	zm.SetClusterCacheSize(maxBytes)
+	expectStats(4, 2, 1)
+	// the least recently used xz cluster was removed
+	readBlob(2)
+	expectStats(5, 2, 1)
+	readBlob(1)
+	readBlob(2)
+	expectStats(5, 4, 1)
+
+	zm.SetClusterCacheSize(0)
+	readBlob(1)
+	expectStats(5, 4, 0)
+}
+
+func TestClusterCacheConcurrent(t *testing.T) {
+	var zc, openErr = Open(path.Join("testdata", filenameTestfile))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zc.Close()
+	zc.SetClusterCacheSize(32 << 20)
+
+	var compressed = make(map[uint32]Cluster)
+	for position := uint32(0); position < zc.ClusterCount(); position++ {
+		var cluster, clusterErr = zc.ClusterAt(position)
+		if clusterErr != nil {
+			t.Fatal(clusterErr)
+		}
+		if cluster.WasCompressed() {
+			compressed[position] = cluster
+		}
+	}
+
+	const goroutines = 8
+	var wg sync.WaitGroup
+	var errs = make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for position, cluster := range compressed {
+				for blobPosition := uint32(0); blobPosition < 20; blobPosition++ {
+					var expected, blobErr = cluster.BlobAt(blobPosition)
+					if blobErr != nil {
+						break
+					}
+					var reader, _, readerErr = zc.BlobReaderAt(position, blobPosition)
+					if readerErr != nil {
+						errs <- readerErr
+						return
+					}
+					if data, _ := ioutil.ReadAll(reader); !bytes.Equal(data, expected) {
+						errs <- fmt.Errorf("blob %d of cluster %d differs", blobPosition, position)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if stats := zc.ClusterCacheStats(); stats.Misses != uint64(len(compressed)) {
+		t.Errorf("zc.ClusterCacheStats() returned %d misses; want %d", stats.Misses, len(compressed))
+	}
+}
+
+func TestClusterCacheLargeLastCluster(t *testing.T) {
+	// without the image and the front article listing all blobs are in a single compressed cluster
+	var entries []writerTestEntry
+	for _, e := range writerTestEntries {
+		if e.mimetype != "image/png" {
+			e.front = false
+			entries = append(entries, e)
+		}
+	}
+	var data = writeTestFile(t, WriterOptions{Compression: CompressionZstd}, entries)
+	// 33MB between the last cluster and the checksum make its estimated size exceed 32MB
+	var checksumPos = binary.LittleEndian.Uint64(data[72:80])
+	var padded = make([]byte, 0, len(data)+33<<20)
+	padded = append(padded, data[:checksumPos]...)
+	padded = append(padded, make([]byte, 33<<20)...)
+	padded = append(padded, data[checksumPos:]...)
+	binary.LittleEndian.PutUint64(padded[72:80], checksumPos+33<<20)
+
+	var zp, err = OpenReaderAt(bytes.NewReader(padded), int64(len(padded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last = zp.ClusterCount() - 1
+	if section, information, _ := zp.clusterSection(last); clusterCompression(information) <= 1 || section.Size() <= maxClusterLen {
+		t.Fatalf("last cluster %d is not a compressed cluster larger than 32MB", last)
+	}
+	zp.SetClusterCacheSize(1 << 20)
+	var found bool
+	for _, e := range entries {
+		var entry, _, entryErr = zp.EntryWithURL(e.namespace, []byte(e.url))
+		if entryErr != nil {
+			t.Fatal(entryErr)
+		}
+		if entry.ClusterNumber() != last {
+			continue
+		}
+		found = true
+		var reader, _, readerErr = zp.BlobReader(&entry)
+		if readerErr != nil {
+			t.Fatalf("zp.BlobReader(%s) returned `%v`", entry.String(), readerErr)
+		}
+		if content, _ := ioutil.ReadAll(reader); string(content) != e.content {
+			t.Errorf("content of %s differs", entry.String())
+		}
+	}
+	if !found {
+		t.Fatal("no Directory Entry in the last cluster")
+	}
+	if stats := zp.ClusterCacheStats(); stats.Misses != 0 || stats.Clusters != 0 {
+		t.Errorf("zp.ClusterCacheStats() returned %+v; want an unused cache", stats)
+	}
+}
+
+func TestClusterCacheTooSmall(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	var minBytes = int64(maxClusterLen)
+	for position := uint32(0); position < zm.ClusterCount(); position++ {
+		var cluster, err = zm.ClusterAt(position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var size, sizeErr = zm.clusterDataSize(position)
+		if sizeErr != nil || size != int64(len(cluster.data)) {
+			t.Errorf("zm.clusterDataSize(%d) returned %d, `%v`; want %d", position, size, sizeErr, len(cluster.data))
+		}
+		if cluster.WasCompressed() && int64(len(cluster.data)) < minBytes {
+			minBytes = int64(len(cluster.data))
+		}
+	}
+
+	// clusters larger than the cache are never decompressed as a whole
+	zm.SetClusterCacheSize(minBytes - 1)
+	for i := 0; i < 3; i++ {
+		for position := uint32(0); position < zm.ClusterCount(); position++ {
+			var cluster, _ = zm.ClusterAt(position)
+			var expected, _ = cluster.BlobAt(0)
+			var reader, _, err = zm.BlobReaderAt(position, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := ioutil.ReadAll(reader); !bytes.Equal(data, expected) {
+				t.Errorf("blob of cluster %d differs", position)
+			}
+		}
+	}
+	if stats := zm.ClusterCacheStats(); stats.Misses != 0 || stats.Clusters != 0 {
+		t.Errorf("zm.ClusterCacheStats() returned %+v; want no misses", stats)
+	}
+}
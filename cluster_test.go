@@ -2,6 +2,8 @@ package zim
 
 import (
 	"bytes"
+	"io/ioutil"
+	"path"
 	"testing"
 )
 
@@ -35,3 +37,92 @@ func TestClusterAt(t *testing.T) {
 		t.Errorf("Number of HTML files in ZIM file was %d; want %d\n", numberHTMLFiles, expectedNumberHTMLFiles)
 	}
 }
+
+const filenameMixedCompression = `mixed_xz_zstd.zim`
+
+func TestMixedCompression(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	if title := zm.Title(); title != "Mixed compression test" {
+		t.Errorf("zm.Title() was `%s`; want `%s`", title, "Mixed compression test")
+	}
+
+	var expectedCompressed = []bool{false, true, true}
+	if count := zm.ClusterCount(); count != uint32(len(expectedCompressed)) {
+		t.Fatalf("zm.ClusterCount() = %d; want %d", count, len(expectedCompressed))
+	}
+	for position, compressed := range expectedCompressed {
+		var cluster, clusterErr = zm.ClusterAt(uint32(position))
+		if clusterErr != nil {
+			t.Errorf("error at cluster position %d: %s\n", position, clusterErr)
+			continue
+		}
+		if cluster.WasCompressed() != compressed {
+			t.Errorf("cluster.WasCompressed() at position %d was %t; want %t", position, cluster.WasCompressed(), compressed)
+		}
+	}
+
+	// alternate between xz and zstd clusters so the decoders are reset several times
+	var tests = []struct {
+		namespace Namespace
+		url       string
+		contains  string
+		size      int64
+	}{
+		{NamespaceArticles, "index.html", "<p>xz compressed paragraph</p>", 1259},
+		{NamespaceArticles, "zstd.html", "<p>zstd compressed paragraph</p>", 1343},
+		{NamespaceLayout, "style.css", "p { margin: 0; }", 510},
+		{NamespaceArticles, "notes.txt", "zstd plain text line", 525},
+		{NamespaceArticles, "index.html", "<p>xz compressed paragraph</p>", 1259},
+	}
+	for _, test := range tests {
+		var entry, _, entryErr = zm.EntryWithURL(test.namespace, []byte(test.url))
+		if entryErr != nil {
+			t.Errorf("Directory Entry with URL `%s/%s` not found: %s", test.namespace, test.url, entryErr)
+			continue
+		}
+		var blobReader, blobSize, blobReaderErr = zm.BlobReader(&entry)
+		if blobReaderErr != nil {
+			t.Errorf("zm.BlobReader() for `%s` failed: %s", test.url, blobReaderErr)
+			continue
+		}
+		var data, readErr = ioutil.ReadAll(blobReader)
+		if readErr != nil {
+			t.Errorf("reading blob of `%s` failed: %s", test.url, readErr)
+		}
+		if blobSize != test.size || int64(len(data)) != test.size {
+			t.Errorf("blob of `%s` has size %d (read %d); want %d", test.url, blobSize, len(data), test.size)
+		}
+		if !bytes.Contains(data, []byte(test.contains)) {
+			t.Errorf("blob of `%s` doesn't contain `%s`", test.url, test.contains)
+		}
+
+		var cluster, clusterErr = zm.ClusterAt(entry.ClusterNumber())
+		if clusterErr != nil {
+			t.Error(clusterErr)
+			continue
+		}
+		var blob, blobErr = cluster.BlobAt(entry.BlobNumber())
+		if blobErr != nil {
+			t.Error(blobErr)
+		} else if !bytes.Equal(blob, data) {
+			t.Errorf("cluster.BlobAt() and zm.BlobReader() differ for `%s`", test.url)
+		}
+	}
+
+	var redirectEntry, _, redirectEntryErr = zm.EntryWithURL(NamespaceArticles, []byte("Redirect.html"))
+	if redirectEntryErr != nil {
+		t.Fatalf("Directory Entry with URL `Redirect.html` not found: %s", redirectEntryErr)
+	}
+	var targetEntry, redirectErr = zm.FollowRedirect(&redirectEntry)
+	if redirectErr != nil {
+		t.Error(redirectErr)
+	}
+	if url := string(targetEntry.URL()); url != "zstd.html" {
+		t.Errorf("Target Directory Entry has URL `%s`; want `%s`", url, "zstd.html")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readMetadataFile reads the metadata keys and values of a JSON file (with extension .json)
+// or a YAML file. Values of JSON arrays and YAML lists are joined with ";" like the Tags.
+func readMetadataFile(filename string) (map[string]string, error) {
+	var data, err = os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		return parseJSONMetadata(data)
+	}
+	return parseYAMLMetadata(data)
+}
+
+func parseJSONMetadata(data []byte) (map[string]string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	var metadata = make(map[string]string, len(values))
+	for key, value := range values {
+		switch value := value.(type) {
+		case string:
+			metadata[key] = value
+		case float64, bool:
+			metadata[key] = fmt.Sprint(value)
+		case []interface{}:
+			var items = make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			metadata[key] = strings.Join(items, ";")
+		default:
+			return nil, fmt.Errorf("metadata %s: unsupported JSON value", key)
+		}
+	}
+	return metadata, nil
+}
+
+// parseYAMLMetadata parses the subset of YAML needed for metadata: a mapping of keys to
+// plain or quoted scalars, literal (|) and folded (>) block scalars, flow lists ([a, b])
+// and block lists (- a).
+func parseYAMLMetadata(data []byte) (map[string]string, error) {
+	var metadata = make(map[string]string)
+	var lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		var line = strings.TrimRight(lines[i], " \t")
+		var trimmed = strings.TrimSpace(line)
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if trimmed != line {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+		var key, value, found = strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: missing ':'", i+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		// the indented lines following the key belong to its value
+		var block []string
+		for i+1 < len(lines) {
+			var next = strings.TrimRight(lines[i+1], " \t\r")
+			if len(next) > 0 && next[0] != ' ' && next[0] != '\t' && !strings.HasPrefix(next, "- ") {
+				break
+			}
+			block = append(block, next)
+			i++
+		}
+		for len(block) > 0 && len(strings.TrimSpace(block[len(block)-1])) == 0 {
+			block = block[:len(block)-1]
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			metadata[key] = blockScalar(block, value[0] == '>')
+			continue
+		case len(value) == 0 && len(block) > 0:
+			metadata[key], err = blockList(block)
+		case len(block) > 0:
+			return nil, fmt.Errorf("line %d: multi-line plain values are not supported", i+1)
+		case strings.HasPrefix(value, "["):
+			metadata[key], err = flowList(value)
+		default:
+			metadata[key], err = scalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("metadata %s: %v", key, err)
+		}
+	}
+	return metadata, nil
+}
+
+// blockScalar joins the lines of a block scalar without their common indentation.
+// Folded lines are joined by spaces, empty lines become newlines.
+func blockScalar(lines []string, folded bool) string {
+	var indent = -1
+	for _, line := range lines {
+		if trimmed := strings.TrimLeft(line, " \t"); len(trimmed) > 0 {
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	var text strings.Builder
+	for i, line := range lines {
+		if len(line) >= indent {
+			line = line[indent:]
+		} else {
+			line = ""
+		}
+		if i > 0 {
+			switch {
+			case !folded || len(line) == 0:
+				text.WriteByte('\n')
+			case len(strings.TrimSpace(lines[i-1])) > 0:
+				text.WriteByte(' ')
+			}
+		}
+		text.WriteString(line)
+	}
+	return text.String()
+}
+
+func blockList(lines []string) (string, error) {
+	var items []string
+	for _, line := range lines {
+		var item = strings.TrimSpace(line)
+		if len(item) == 0 || strings.HasPrefix(item, "#") {
+			continue
+		}
+		if !strings.HasPrefix(item, "-") {
+			return "", fmt.Errorf("nested mappings are not supported")
+		}
+		var value, err = scalar(strings.TrimSpace(item[1:]))
+		if err != nil {
+			return "", err
+		}
+		items = append(items, value)
+	}
+	return strings.Join(items, ";"), nil
+}
+
+func flowList(value string) (string, error) {
+	if !strings.HasSuffix(value, "]") {
+		return "", fmt.Errorf("unterminated list")
+	}
+	var items []string
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+		var value, err = scalar(item)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, value)
+	}
+	return strings.Join(items, ";"), nil
+}
+
+// scalar returns the value of a single-line plain, single-quoted or double-quoted scalar.
+func scalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		var end = strings.LastIndexByte(value, '"')
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		var end = strings.LastIndexByte(value, '\'')
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strings.ReplaceAll(value[1:end], "''", "'"), nil
+	}
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value, nil
+}
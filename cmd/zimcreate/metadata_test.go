@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseYAMLMetadata(t *testing.T) {
+	for _, test := range []struct {
+		yaml string
+		want string // the metadata as formatted by fmt; empty if an error is expected
+	}{
+		{"Title: Hello\nLanguage: eng # ISO 639-3\n", "map[Language:eng Title:Hello]"},
+		{"---\nTitle: Front matter\n---\n", "map[Title:Front matter]"},
+		{"# comment\n\nTitle: x\r\nName: y\r\n", "map[Name:y Title:x]"},
+		{"Source: https://example.com/a#b\nTags: a#b", "map[Source:https://example.com/a#b Tags:a#b]"},
+		{`Title: "A \"quoted\" title"` + "\nCreator: 'It''s me'", `map[Creator:It's me Title:A "quoted" title]`},
+		{"Title:\nName: x", "map[Name:x Title:]"},
+		{"LongDescription: |\n  line one\n    line two\n\nTitle: t", "map[LongDescription:line one\n  line two Title:t]"},
+		{"Description: >\n  folded\n  text\n\n  paragraph\n", "map[Description:folded text\nparagraph]"},
+		{"Tags:\n  - a\n  - 'b c' # comment\n", "map[Tags:a;b c]"},
+		{"Tags:\n- a\n- b\nTitle: t", "map[Tags:a;b Title:t]"},
+		{`Tags: [a, 'b c', "d", ]`, "map[Tags:a;b c;d]"},
+		{"  Title: indented", ""},
+		{"Title", ""},
+		{"Title: a\n  b", ""},
+		{"Tags: [a, b", ""},
+		{`Title: "unterminated`, ""},
+		{"Tags:\n  key: value", ""},
+	} {
+		var metadata, err = parseYAMLMetadata([]byte(test.yaml))
+		var got string
+		if err == nil {
+			got = fmt.Sprint(metadata)
+		}
+		if got != test.want {
+			t.Errorf("parseYAMLMetadata(%q) returned %q, `%v`; want %q", test.yaml, got, err, test.want)
+		}
+	}
+}
+
+func TestParseJSONMetadata(t *testing.T) {
+	for _, test := range []struct {
+		json string
+		want string
+	}{
+		{`{"Title": "x", "Tags": ["a", "b"], "Count": 3, "Flag": true}`, "map[Count:3 Flag:true Tags:a;b Title:x]"},
+		{`{"Title": {"en": "x"}}`, ""},
+		{`{"Title": null}`, ""},
+		{`["Title"]`, ""},
+	} {
+		var metadata, err = parseJSONMetadata([]byte(test.json))
+		var got string
+		if err == nil {
+			got = fmt.Sprint(metadata)
+		}
+		if got != test.want {
+			t.Errorf("parseJSONMetadata(%s) returned %q, `%v`; want %q", test.json, got, err, test.want)
+		}
+	}
+}
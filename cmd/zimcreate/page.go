@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// detectMimetype returns the mimetype of the file from its extension or,
+// if the extension is unknown, from the first bytes of its contents.
+// Parameters like the charset are removed.
+func detectMimetype(filename string, data []byte) string {
+	var mimetype = mime.TypeByExtension(strings.ToLower(filepath.Ext(filename)))
+	if len(mimetype) == 0 {
+		mimetype = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimetype); err == nil {
+		mimetype = mediaType
+	}
+	return mimetype
+}
+
+// isHTML reports whether pages with the mimetype are parsed for their title.
+func isHTML(mimetype string) bool {
+	return mimetype == "text/html" || mimetype == "application/xhtml+xml"
+}
+
+// pageInfo returns the text of the <title> element of an HTML page and the URL
+// of a <meta http-equiv="refresh"> element; both are empty if they are missing.
+// Only the head of the page is parsed.
+func pageInfo(data []byte) (title, refresh string) {
+	var tokenizer = html.NewTokenizer(bytes.NewReader(data))
+	var inTitle, titleDone bool
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(title), " "), refresh
+		case html.StartTagToken, html.SelfClosingTagToken:
+			var token = tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = !titleDone
+			case atom.Meta:
+				if strings.EqualFold(attribute(token, "http-equiv"), "refresh") && len(refresh) == 0 {
+					refresh = refreshURL(attribute(token, "content"))
+				}
+			case atom.Body:
+				return strings.Join(strings.Fields(title), " "), refresh
+			}
+		case html.EndTagToken:
+			if inTitle && tokenizer.Token().DataAtom == atom.Title {
+				inTitle, titleDone = false, true
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+			}
+		}
+	}
+}
+
+func attribute(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// refreshURL returns the URL of the content of a meta refresh like "0; url=page.html".
+func refreshURL(content string) string {
+	var _, target, found = strings.Cut(content, ";")
+	if !found {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if len(target) < 4 || !strings.EqualFold(target[:3], "url") {
+		return ""
+	}
+	target = strings.TrimSpace(target[3:])
+	if !strings.HasPrefix(target, "=") {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(target[1:]), `"'`)
+}
+
+// resolveLink returns the URL inside the directory, which the link of the page with pageURL
+// points to. It returns false for external links, links leaving the directory and links
+// to the directory itself or the page itself.
+func resolveLink(pageURL, link string) (string, bool) {
+	var u, err = url.Parse(link)
+	if err != nil || u.IsAbs() || len(u.Host) > 0 || len(u.Path) == 0 {
+		return "", false
+	}
+	var target = u.Path
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(pageURL), target)
+	}
+	target = strings.TrimPrefix(path.Clean(target), "/")
+	if len(target) == 0 || target == "." || target == ".." || strings.HasPrefix(target, "../") || target == pageURL {
+		return "", false
+	}
+	return target, true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDetectMimetype(t *testing.T) {
+	for _, test := range []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{"index.html", "", "text/html"},
+		{"STYLE.CSS", "", "text/css"},
+		{"image", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", "image/png"},
+		{"notes", "plain text", "text/plain"},
+	} {
+		if got := detectMimetype(test.filename, []byte(test.data)); got != test.want {
+			t.Errorf("detectMimetype(%s) returned `%s`; want `%s`", test.filename, got, test.want)
+		}
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	for _, test := range []struct {
+		html    string
+		title   string
+		refresh string
+	}{
+		{"<html><head><title> A \n  title </title></head><body>text</body></html>", "A title", ""},
+		{"<title>first</title><title>second</title>", "first", ""},
+		{"<head></head><body><title>in the body</title></body>", "", ""},
+		{`<head><meta http-equiv="Refresh" content="0; URL='docs/'"><title>Moved</title></head>`, "Moved", "docs/"},
+		{`<meta http-equiv="refresh" content="5">`, "", ""},
+		{`<meta content="0; url=a.html"><meta http-equiv="refresh" content="0; url=b.html">` +
+			`<meta http-equiv="refresh" content="0; url=c.html">`, "", "b.html"},
+	} {
+		if title, refresh := pageInfo([]byte(test.html)); title != test.title || refresh != test.refresh {
+			t.Errorf("pageInfo(%s) returned `%s`, `%s`; want `%s`, `%s`", test.html, title, refresh, test.title, test.refresh)
+		}
+	}
+}
+
+func TestRefreshURL(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		want    string
+	}{
+		{"0; url=page.html", "page.html"},
+		{"0;URL = 'a b.html'", "a b.html"},
+		{`3; url="../index.html"`, "../index.html"},
+		{"0", ""},
+		{"0; page.html", ""},
+		{"0; url", ""},
+		{"0; urlx=page.html", ""},
+	} {
+		if got := refreshURL(test.content); got != test.want {
+			t.Errorf("refreshURL(%s) returned `%s`; want `%s`", test.content, got, test.want)
+		}
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	for _, test := range []struct {
+		page string
+		link string
+		want string // empty if the link is not resolved
+	}{
+		{"docs/a.html", "b.html", "docs/b.html"},
+		{"docs/a.html", "../index.html?lang=en#top", "index.html"},
+		{"docs/a.html", "/style.css", "style.css"},
+		{"docs/a.html", "./", "docs"},
+		{"a.html", "b%20c.html", "b c.html"},
+		{"a.html", "https://example.com/", ""},
+		{"a.html", "//example.com/b.html", ""},
+		{"a.html", "mailto:someone@example.com", ""},
+		{"a.html", "#top", ""},
+		{"a.html", "a.html#top", ""},
+		{"a.html", "../b.html", ""},
+		{"docs/a.html", "/..", ""},
+		{"a.html", "./", ""},
+	} {
+		var got, ok = resolveLink(test.page, test.link)
+		if !ok {
+			got = ""
+		}
+		if got != test.want || ok != (len(test.want) > 0) {
+			t.Errorf("resolveLink(%s, %s) returned `%s`, %t; want `%s`", test.page, test.link, got, ok, test.want)
+		}
+	}
+}
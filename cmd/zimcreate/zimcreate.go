@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/png"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dps/go-zim"
+)
+
+// metadataFlags are the metadata, which can be set by flags.
+var metadataFlags = [...]struct{ flag, key, usage string }{
+	{"name", "Name", "Identifier of the content, like \"docs_en_all\"."},
+	{"title", "Title", "Title of the ZIM file (at most 30 characters)."},
+	{"description", "Description", "Description of the content (at most 80 characters)."},
+	{"longdescription", "LongDescription", "Long description of the content."},
+	{"language", "Language", "ISO 639-3 language codes of the content, like \"eng\" or \"eng,deu\"."},
+	{"creator", "Creator", "Creator of the content."},
+	{"publisher", "Publisher", "Publisher of the ZIM file."},
+	{"date", "Date", "Date of the content as YYYY-MM-DD (default today)."},
+	{"tags", "Tags", "Tags separated by \";\"."},
+	{"source", "Source", "URL of the source of the content."},
+	{"flavour", "Flavour", "Flavour of the content, like \"nopic\"."},
+	{"license", "License", "License of the content."},
+}
+
+// page is an HTML page, which redirects to another page by a meta refresh.
+type page struct {
+	url, title, target string
+}
+
+func main() {
+
+	var dir string
+	var filenameZim string
+	var mainPage string
+	var illustration string
+	var metadataFile string
+	var compression string
+	var level int
+	var workers int
+	var clusterSize int
+
+	flag.StringVar(&dir, "dir", "", "Directory with the HTML pages and their resources.")
+	flag.StringVar(&filenameZim, "zim", "", "Path of the ZIM file to create.")
+	flag.StringVar(&mainPage, "main", "index.html", "Path of the main page relative to the directory.")
+	flag.StringVar(&illustration, "illustration", "", "Path of the 48x48 PNG illustration of the ZIM file.")
+	flag.StringVar(&metadataFile, "metadata", "",
+		"Path of a YAML or JSON file with the metadata; the metadata flags take precedence.")
+	flag.StringVar(&compression, "compression", "zstd", "Compression of the clusters: zstd, xz or none.")
+	flag.IntVar(&level, "level", 0, "Compression level; 0 means the default level.")
+	flag.IntVar(&workers, "workers", 0, "Number of clusters compressed concurrently; 0 means one per CPU.")
+	flag.IntVar(&clusterSize, "clustersize", 2048, "Size of the clusters in KB.")
+	var metadataValues = make(map[string]*string, len(metadataFlags))
+	for _, m := range metadataFlags {
+		metadataValues[m.key] = flag.String(m.flag, "", m.usage)
+	}
+	flag.Parse()
+
+	if len(dir) == 0 || len(filenameZim) == 0 {
+		flag.PrintDefaults()
+		return
+	}
+
+	var metadata = make(map[string]string)
+	if len(metadataFile) > 0 {
+		var err error
+		if metadata, err = readMetadataFile(metadataFile); err != nil {
+			log.Fatalf("%s: %v", metadataFile, err)
+		}
+		// the illustration of the metadata file is relative to the file
+		if value, found := metadata["Illustration"]; found {
+			delete(metadata, "Illustration")
+			if len(illustration) == 0 && len(value) > 0 {
+				illustration = value
+				if !filepath.IsAbs(illustration) {
+					illustration = filepath.Join(filepath.Dir(metadataFile), illustration)
+				}
+			}
+		}
+	}
+	for key, value := range metadataValues {
+		if len(*value) > 0 {
+			metadata[key] = *value
+		}
+	}
+	if len(metadata["Date"]) == 0 {
+		metadata["Date"] = time.Now().Format("2006-01-02")
+	}
+
+	var options = zim.WriterOptions{Workers: workers, CompressionLevel: level, ClusterSize: clusterSize << 10}
+	switch compression {
+	case "zstd":
+		options.Compression = zim.CompressionZstd
+	case "xz":
+		options.Compression = zim.CompressionXz
+	case "none":
+		options.Compression = zim.CompressionNone
+	default:
+		log.Fatalf("unknown compression %q", compression)
+	}
+
+	var out, createErr = os.Create(filenameZim)
+	if createErr != nil {
+		log.Fatal(createErr)
+	}
+	if err := create(out, dir, mainPage, illustration, metadata, options); err != nil {
+		out.Close()
+		os.Remove(filenameZim)
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	var z, zimOpenErr = zim.Open(filenameZim)
+	if zimOpenErr != nil {
+		log.Fatal(zimOpenErr)
+	}
+	defer z.Close()
+	for _, violation := range z.ValidateMetadata() {
+		log.Println(violation)
+	}
+	fmt.Printf("Created %s with %d entries in %d clusters\n", filenameZim, z.ArticleCount(), z.ClusterCount())
+}
+
+// create writes the ZIM file with the files of the directory to out.
+func create(out *os.File, dir, mainPage, illustration string, metadata map[string]string,
+	options zim.WriterOptions) error {
+
+	var zw, err = zim.NewWriter(out, options)
+	if err != nil {
+		return err
+	}
+	// nothing is written to out, if adding the files fails
+	defer zw.Abort()
+
+	var outPath, _ = filepath.Abs(out.Name())
+	var urls = make(map[string]bool)
+	var redirects []page
+	err = filepath.WalkDir(dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		if absPath, _ := filepath.Abs(filename); absPath == outPath {
+			return nil
+		}
+		var rel, relErr = filepath.Rel(dir, filename)
+		if relErr != nil {
+			return relErr
+		}
+		var url = filepath.ToSlash(rel)
+		var data, readErr = os.ReadFile(filename)
+		if readErr != nil {
+			return readErr
+		}
+		urls[url] = true
+
+		var entry = zim.WriterEntry{Namespace: zim.NamespaceContent, URL: url,
+			Mimetype: detectMimetype(filename, data), Content: bytes.NewReader(data)}
+		if isHTML(entry.Mimetype) {
+			var refresh string
+			entry.Title, refresh = pageInfo(data)
+			if target, ok := resolveLink(url, refresh); ok {
+				redirects = append(redirects, page{url, entry.Title, target})
+				return nil
+			}
+			entry.FrontArticle = true
+		}
+		return zw.AddEntry(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	// pages redirecting to a missing page are kept as they are
+	for _, redirect := range redirects {
+		var target = redirect.target
+		if !urls[target] {
+			target = path.Join(target, "index.html")
+		}
+		if urls[target] {
+			err = zw.AddRedirect(zim.WriterRedirect{Namespace: zim.NamespaceContent, URL: redirect.url,
+				Title: redirect.title, TargetNamespace: zim.NamespaceContent, TargetURL: target})
+		} else {
+			log.Printf("%s: redirect target %s not found", redirect.url, redirect.target)
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(redirect.url))); err == nil {
+				err = zw.AddEntry(zim.WriterEntry{Namespace: zim.NamespaceContent, URL: redirect.url,
+					Title: redirect.title, Mimetype: "text/html", Content: bytes.NewReader(data), FrontArticle: true})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if urls[mainPage] {
+		zw.SetMainPage(zim.NamespaceContent, mainPage)
+	} else {
+		log.Printf("main page %s not found", mainPage)
+	}
+
+	if len(illustration) > 0 {
+		var data, err = os.ReadFile(illustration)
+		if err != nil {
+			return err
+		}
+		var config, configErr = png.DecodeConfig(bytes.NewReader(data))
+		if configErr != nil {
+			return fmt.Errorf("illustration %s: %v", illustration, configErr)
+		}
+		if config.Width != config.Height {
+			return fmt.Errorf("illustration %s is not square", illustration)
+		}
+		if err = zw.SetIllustration(config.Width, 1, data); err != nil {
+			return err
+		}
+	}
+
+	var keys = make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err = zw.SetMetadata(key, strings.TrimSpace(metadata[key])); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dps/go-zim"
+)
+
+func TestCreate(t *testing.T) {
+	var dir = t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":      `<html><head><title>Home</title><link rel="stylesheet" href="style.css"></head><body>home</body></html>`,
+		"style.css":       "body { margin: 0; }",
+		"old.html":        `<html><head><title>Old</title><meta http-equiv="refresh" content="0; url=docs/"></head></html>`,
+		"broken.html":     `<html><head><title>Broken</title><meta http-equiv="refresh" content="0; url=missing.html"></head></html>`,
+		"docs/index.html": `<html><head><title>Docs</title></head><body>docs</body></html>`,
+	} {
+		var filename = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var outDir = t.TempDir()
+	var illustration = filepath.Join(outDir, "illustration.png")
+	var f, err = os.Create(illustration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = png.Encode(f, image.NewGray(image.Rect(0, 0, 48, 48))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var filenameZim = filepath.Join(outDir, "test.zim")
+	var out, createErr = os.Create(filenameZim)
+	if createErr != nil {
+		t.Fatal(createErr)
+	}
+	var metadata = map[string]string{"Title": " Create test ", "Language": "eng", "Date": "2026-10-16"}
+	if err = create(out, dir, "index.html", illustration, metadata, zim.WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	var z, openErr = zim.Open(filenameZim)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer z.Close()
+	if err = z.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+	if z.Title() != "Create test" || z.MetadataFor("Language") != "eng" {
+		t.Errorf("metadata of the created file is %v", z.Metadata())
+	}
+	if data, err := z.Illustration(48, 1); err != nil || len(data) == 0 {
+		t.Errorf("z.Illustration(48, 1) returned %d bytes, `%v`", len(data), err)
+	}
+	if mainPage, err := z.MainPage(); err != nil || string(mainPage.URL()) != "index.html" {
+		t.Errorf("z.MainPage() returned %s, `%v`", mainPage.String(), err)
+	}
+	for url, want := range map[string]struct {
+		title, mimetype, target string
+	}{
+		"index.html":      {"Home", "text/html", ""},
+		"style.css":       {"style.css", "text/css", ""},
+		"old.html":        {"Old", "", "docs/index.html"},
+		"broken.html":     {"Broken", "text/html", ""},
+		"docs/index.html": {"Docs", "text/html", ""},
+	} {
+		var entry, _, err = z.EntryWithURL(zim.NamespaceContent, []byte(url))
+		if err != nil {
+			t.Errorf("z.EntryWithURL(C, %s) returned `%v`", url, err)
+			continue
+		}
+		if string(entry.Title()) != want.title {
+			t.Errorf("%s has the title `%s`; want `%s`", url, entry.Title(), want.title)
+		}
+		if len(want.target) > 0 {
+			var target, err = z.FollowRedirect(&entry)
+			if err != nil || string(target.URL()) != want.target {
+				t.Errorf("%s redirects to %s, `%v`; want %s", url, target.String(), err, want.target)
+			}
+			continue
+		}
+		if mimetype, err := z.MimetypeOf(&entry); err != nil || mimetype != want.mimetype {
+			t.Errorf("%s has the mimetype `%s`, `%v`; want `%s`", url, mimetype, err, want.mimetype)
+		}
+	}
+
+	// nothing is written, if creating the file fails
+	var failed = filepath.Join(outDir, "failed.zim")
+	if out, err = os.Create(failed); err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if err = create(out, dir, "index.html", filepath.Join(outDir, "missing.png"), metadata, zim.WriterOptions{}); err == nil {
+		t.Error("create() with a missing illustration succeeded")
+	}
+	if info, err := out.Stat(); err != nil || info.Size() != 0 {
+		t.Errorf("create() wrote %d bytes after an error, `%v`", info.Size(), err)
+	}
+}
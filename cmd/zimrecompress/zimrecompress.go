@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dps/go-zim"
+)
+
+func main() {
+
+	var filenameZim string
+	var filenameOut string
+	var compression string
+	var level int
+	var workers int
+	var clusterSize int
+	var keepUUID bool
+	var verify bool
+
+	flag.StringVar(&filenameZim, "zim", "", "Path to the ZIM file to read from.")
+	flag.StringVar(&filenameOut, "out", "", "Path of the recompressed ZIM file to create.")
+	flag.StringVar(&compression, "compression", "zstd", "Compression of the clusters: zstd, xz or none.")
+	flag.IntVar(&level, "level", 0, "Compression level; 0 means the default level.")
+	flag.IntVar(&workers, "workers", 0, "Number of clusters compressed concurrently; 0 means one per CPU.")
+	flag.IntVar(&clusterSize, "clustersize", 2048, "Size of the clusters in KB.")
+	flag.BoolVar(&keepUUID, "keepuuid", false, "Keep the UUID of the ZIM file instead of creating a new one.")
+	flag.BoolVar(&verify, "verify", true, "Compare the recompressed file entry by entry with the ZIM file.")
+	flag.Parse()
+
+	if len(filenameZim) == 0 || len(filenameOut) == 0 {
+		flag.PrintDefaults()
+		return
+	}
+
+	var options = zim.WriterOptions{Workers: workers, CompressionLevel: level, ClusterSize: clusterSize << 10}
+	switch compression {
+	case "zstd":
+		options.Compression = zim.CompressionZstd
+	case "xz":
+		options.Compression = zim.CompressionXz
+	case "none":
+		options.Compression = zim.CompressionNone
+	default:
+		log.Fatalf("unknown compression %q", compression)
+	}
+
+	var z, zimOpenErr = zim.OpenMmap(filenameZim)
+	if zimOpenErr != nil {
+		log.Fatal(zimOpenErr)
+	}
+	defer z.Close()
+	if keepUUID {
+		options.UUID = z.UUID()
+	}
+
+	var out, createErr = os.Create(filenameOut)
+	if createErr != nil {
+		log.Fatal(createErr)
+	}
+	if err := z.Recompress(out, options); err != nil {
+		out.Close()
+		os.Remove(filenameOut)
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	var recompressed, openErr = zim.OpenMmap(filenameOut)
+	if openErr != nil {
+		log.Fatal(openErr)
+	}
+	defer recompressed.Close()
+	if verify {
+		if err := recompressed.ValidateChecksum(); err != nil {
+			log.Fatal(err)
+		}
+		if err := z.CompareEntries(recompressed); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("%s: %d bytes in %d clusters\n", filenameZim, z.Filesize(), z.ClusterCount())
+	fmt.Printf("%s: %d bytes in %d clusters\n", filenameOut, recompressed.Filesize(), recompressed.ClusterCount())
+}
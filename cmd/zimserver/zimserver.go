@@ -1,13 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/dps/go-zim"
 )
@@ -16,9 +16,11 @@ func main() {
 
 	var filename string
 	var port int
+	var cacheSize int
 
-	flag.StringVar(&filename, "filename", "", "Filename of the ZIM file to use.")
+	flag.StringVar(&filename, "filename", "", "Filename or HTTP URL of the ZIM file to use.")
 	flag.IntVar(&port, "port", 8080, "TCP port of the HTTP server.")
+	flag.IntVar(&cacheSize, "cache", 64, "Size in MB of the cache of decompressed clusters.")
 
 	flag.Parse()
 
@@ -27,9 +29,15 @@ func main() {
 		return
 	}
 
-	if z, zimOpenErr := zim.Open(filename); zimOpenErr != nil {
+	var open = zim.OpenMmap
+	if strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://") {
+		open = zim.OpenURL
+	}
+
+	if z, zimOpenErr := open(filename); zimOpenErr != nil {
 		log.Fatal(zimOpenErr)
 	} else {
+		z.SetClusterCacheSize(int64(cacheSize) << 20)
 		StartHTTPServer(z, uint16(port))
 	}
 
@@ -42,12 +50,14 @@ func StartHTTPServer(z *zim.File, port uint16) {
 	var zimName = z.UUID().String()
 	var zimNameLen = len(zimName)
 	var urlSuffixMainpage []byte
+	var mainPageNamespace zim.Namespace
 	{
 		var mainPageEntry, mainPageErr = z.MainPage()
 		if mainPageErr != nil {
 			log.Println("No Mainpage specified in ZIM file.")
 		}
 		urlSuffixMainpage = mainPageEntry.URL()
+		mainPageNamespace = mainPageEntry.Namespace()
 	}
 
 	var urlPrefix = fmt.Sprintf("/%s/", zimName)
@@ -58,7 +68,7 @@ func StartHTTPServer(z *zim.File, port uint16) {
 
 	fmt.Println(fmt.Sprintf(
 		"Serving ZIM file at http://localhost:%d%s\n",
-		port, createURLFor(zim.NamespaceArticles, urlSuffixMainpage)))
+		port, createURLFor(mainPageNamespace, urlSuffixMainpage)))
 
 	if title := z.Title(); len(title) > 0 {
 		fmt.Println(title)
@@ -72,15 +82,11 @@ func StartHTTPServer(z *zim.File, port uint16) {
 		fmt.Println(date)
 	}
 
-	var mutex sync.Mutex
-
 	log.Fatal(http.ListenAndServe(fmt.Sprint("localhost:", port), http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 
 			if r.URL.Path == "/favicon.ico" {
-				mutex.Lock()
 				favicon, faviconErr := z.Favicon()
-				mutex.Unlock()
 				if faviconErr != nil {
 					log.Println(faviconErr)
 					http.NotFound(w, r)
@@ -91,7 +97,7 @@ func StartHTTPServer(z *zim.File, port uint16) {
 			}
 			if len(r.URL.Path) < zimNameLen+5 || !strings.HasPrefix(r.URL.Path, urlPrefix) ||
 				r.URL.Path[zimNameLen+3] != '/' {
-				http.Redirect(w, r, createURLFor(zim.NamespaceArticles, urlSuffixMainpage), http.StatusFound)
+				http.Redirect(w, r, createURLFor(mainPageNamespace, urlSuffixMainpage), http.StatusFound)
 				return
 			}
 
@@ -101,40 +107,44 @@ func StartHTTPServer(z *zim.File, port uint16) {
 
 			var namespace = zim.Namespace(r.URL.Path[zimNameLen+2])
 			switch namespace {
-			case zim.NamespaceLayout, zim.NamespaceArticles, zim.NamespaceImagesFiles, zim.NamespaceImagesText:
+			case zim.NamespaceLayout, zim.NamespaceArticles, zim.NamespaceImagesFiles, zim.NamespaceImagesText,
+				zim.NamespaceContent, zim.NamespaceZimMetadata:
 				var suffix = []byte(r.URL.Path[zimNameLen+4:])
-				mutex.Lock()
-				var entry, _, found = z.EntryWithURL(namespace, suffix)
-				mutex.Unlock()
-				if found {
+				var entry, _, entryErr = z.EntryWithURL(namespace, suffix)
+				if entryErr == nil {
 					if entry.IsRedirect() {
-						mutex.Lock()
-						entry, _ = z.FollowRedirect(&entry)
-						mutex.Unlock()
+						if entry, entryErr = z.FollowRedirect(&entry); entryErr != nil {
+							log.Printf("Following redirect failed for URL: %s with error %s\n", r.URL.Path, entryErr)
+							http.Error(w, entryErr.Error(), http.StatusInternalServerError)
+							return
+						}
 						http.Redirect(w, r, createURLFor(entry.Namespace(), entry.URL()), http.StatusFound)
 						return
 					}
-					mutex.Lock()
-					var blobReader, _, blobReaderErr = z.BlobReader(&entry)
+					var blobReader, blobReaderErr = z.BlobSeeker(&entry)
 					if blobReaderErr != nil {
-						mutex.Unlock()
 						log.Printf("Entry found but loading blob data failed for URL: %s with error %s\n", r.URL.Path, blobReaderErr)
 						http.Error(w, blobReaderErr.Error(), http.StatusFailedDependency)
 						return
 					}
-					var mimetypeList = z.MimetypeList()
-					if int(entry.Mimetype()) < len(mimetypeList) {
-						w.Header().Set("Content-Type", mimetypeList[entry.Mimetype()])
+					if mimetype, mimetypeErr := z.MimetypeOf(&entry); mimetypeErr == nil {
+						w.Header().Set("Content-Type", mimetype)
 					}
-					io.Copy(w, blobReader)
-					mutex.Unlock()
+					// ServeContent answers Range requests, e.g. for seeking in videos
+					http.ServeContent(w, r, "", time.Time{}, blobReader)
+					return
+				}
+				if !errors.Is(entryErr, zim.ErrNotFound) {
+					log.Printf("Reading entry failed for URL: %s with error %s\n", r.URL.Path, entryErr)
+					http.Error(w, entryErr.Error(), http.StatusInternalServerError)
 					return
 				}
 
-				if namespace == zim.NamespaceArticles {
-					mutex.Lock()
-					var similarEntries = z.EntriesWithSimilarity(namespace, suffix, 100)
-					mutex.Unlock()
+				if namespace == zim.NamespaceArticles || namespace == zim.NamespaceContent {
+					var similarEntries, similarErr = z.EntriesWithSimilarity(namespace, suffix, 100)
+					if similarErr != nil {
+						log.Printf("Searching similar entries failed for URL: %s with error %s\n", r.URL.Path, similarErr)
+					}
 					w.WriteHeader(http.StatusMultipleChoices)
 					w.Write(htmlSuggestions(zimName, similarEntries))
 					return
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/dps/go-zim"
+)
+
+func main() {
+
+	var filenameZim string
+	var asJSON bool
+
+	flag.StringVar(&filenameZim, "zim", "", "Path to the ZIM file to read from.")
+	flag.BoolVar(&asJSON, "json", false, "Print the statistics as JSON.")
+	flag.Parse()
+
+	if len(filenameZim) == 0 {
+		flag.PrintDefaults()
+		return
+	}
+
+	var z, zimOpenErr = zim.OpenMmap(filenameZim)
+	if zimOpenErr != nil {
+		log.Fatal(zimOpenErr)
+	}
+	defer z.Close()
+
+	var stats, statsErr = z.Stats()
+	if statsErr != nil {
+		log.Fatal(statsErr)
+	}
+
+	// namespaces are printed as letters instead of their byte values
+	var namespaces = make(map[string]uint32, len(stats.Namespaces))
+	for namespace, count := range stats.Namespaces {
+		namespaces[string(namespace)] = count
+	}
+
+	if asJSON {
+		var output = struct {
+			zim.Stats
+			Namespaces map[string]uint32
+		}{stats, namespaces}
+		var encoder = json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Printf("Entries:           %d\n", stats.Entries)
+	fmt.Printf("Redirects:         %d\n", stats.Redirects)
+	fmt.Printf("Link targets:      %d\n", stats.LinkTargets)
+	fmt.Printf("Deleted entries:   %d\n", stats.DeletedEntries)
+	fmt.Printf("Invalid mimetypes: %d\n", stats.InvalidMimetypes)
+	fmt.Printf("Clusters:          %d\n", stats.Clusters)
+	fmt.Printf("Blobs:             %d\n", stats.Blobs)
+	fmt.Printf("Blob bytes:        %d\n", stats.BlobBytes)
+
+	fmt.Println("\nNamespaces:")
+	for _, namespace := range sortedKeys(namespaces) {
+		fmt.Printf("  %s  %d\n", namespace, namespaces[namespace])
+	}
+	fmt.Println("\nMimetypes:")
+	for _, mimetype := range sortedKeys(stats.Mimetypes) {
+		fmt.Printf("  %-40s %d\n", mimetype, stats.Mimetypes[mimetype])
+	}
+}
+
+func sortedKeys(m map[string]uint32) []string {
+	var keys = make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
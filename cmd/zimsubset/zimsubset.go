@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dps/go-zim"
+)
+
+func main() {
+
+	var filenameZim string
+	var filenameOut string
+	var filenameTitles string
+	var prefix string
+	var namespace string
+	var withMainPage bool
+	var compression string
+	var clusterSize int
+
+	flag.StringVar(&filenameZim, "zim", "", "Path to the ZIM file to read from.")
+	flag.StringVar(&filenameOut, "out", "", "Path of the ZIM file with the subset to create.")
+	flag.StringVar(&filenameTitles, "titles", "", "Path of a file with the titles of the selected articles, one per line.")
+	flag.StringVar(&prefix, "prefix", "", "Select the Directory Entries with this URL prefix.")
+	flag.StringVar(&namespace, "namespace", "", "Select the Directory Entries of this namespace, like \"A\" or \"C\".")
+	flag.BoolVar(&withMainPage, "mainpage", true, "Select the main page.")
+	flag.StringVar(&compression, "compression", "zstd", "Compression of the clusters: zstd, xz or none.")
+	flag.IntVar(&clusterSize, "clustersize", 2048, "Size of the clusters in KB.")
+	flag.Parse()
+
+	if len(filenameZim) == 0 || len(filenameOut) == 0 ||
+		len(filenameTitles) == 0 && len(prefix) == 0 && len(namespace) == 0 {
+		flag.PrintDefaults()
+		return
+	}
+	if len(namespace) > 1 {
+		log.Fatalf("namespace %q is not a single letter", namespace)
+	}
+
+	var options = zim.WriterOptions{ClusterSize: clusterSize << 10}
+	switch compression {
+	case "zstd":
+		options.Compression = zim.CompressionZstd
+	case "xz":
+		options.Compression = zim.CompressionXz
+	case "none":
+		options.Compression = zim.CompressionNone
+	default:
+		log.Fatalf("unknown compression %q", compression)
+	}
+
+	var titles = make(map[string]bool)
+	if len(filenameTitles) > 0 {
+		var f, err = os.Open(filenameTitles)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var scanner = bufio.NewScanner(f)
+		for scanner.Scan() {
+			if title := strings.TrimSpace(scanner.Text()); len(title) > 0 {
+				titles[title] = true
+			}
+		}
+		f.Close()
+		if err = scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var z, zimOpenErr = zim.OpenMmap(filenameZim)
+	if zimOpenErr != nil {
+		log.Fatal(zimOpenErr)
+	}
+	defer z.Close()
+
+	var mainPage, mainPageErr = z.MainPage()
+	withMainPage = withMainPage && mainPageErr == nil
+	var byURL = len(prefix) > 0 || len(namespace) > 0
+
+	var selected = func(e *zim.DirectoryEntry) bool {
+		switch {
+		case titles[string(e.Title())]:
+			return true
+		case withMainPage && e.Namespace() == mainPage.Namespace() && bytes.Equal(e.URL(), mainPage.URL()):
+			return true
+		case byURL:
+			return (len(namespace) == 0 || e.Namespace() == zim.Namespace(namespace[0])) &&
+				bytes.HasPrefix(e.URL(), []byte(prefix))
+		}
+		return false
+	}
+
+	var out, createErr = os.Create(filenameOut)
+	if createErr != nil {
+		log.Fatal(createErr)
+	}
+	if err := z.Subset(out, selected, options); err != nil {
+		out.Close()
+		os.Remove(filenameOut)
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	var subset, openErr = zim.OpenMmap(filenameOut)
+	if openErr != nil {
+		log.Fatal(openErr)
+	}
+	defer subset.Close()
+	fmt.Printf("%s: %d of %d entries, %d bytes\n", filenameOut, subset.ArticleCount(), z.ArticleCount(),
+		subset.Filesize())
+}
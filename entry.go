@@ -3,6 +3,7 @@ package zim
 import (
 	"errors"
 	"fmt"
+	"io"
 )
 
 // DirectoryEntry holds the information about a specific article, image or other object in a ZIM file.
@@ -15,7 +16,7 @@ type DirectoryEntry struct {
 	blobNumberOrRedirectIndex uint32
 	url                       []byte
 	title                     []byte
-	//parameter     []byte    // (not used) extra parameters; see `parameterLen`
+	parameter                 []byte // extra parameters; len(parameter) == parameterLen
 }
 
 func (e *DirectoryEntry) String() string {
@@ -59,6 +60,13 @@ func (e *DirectoryEntry) URL() []byte {
 	return e.url
 }
 
+// Parameter returns the extra parameter data of the Directory Entry,
+// which is stored after the title. Its meaning is not specified
+// by the ZIM format and most Directory Entries have no parameters.
+func (e *DirectoryEntry) Parameter() []byte {
+	return e.parameter
+}
+
 // Title is the title of the Directory Entry.
 func (e *DirectoryEntry) Title() []byte {
 	if len(e.title) > 0 {
@@ -67,64 +75,115 @@ func (e *DirectoryEntry) Title() []byte {
 	return e.url
 }
 
-func (z *File) readDirectoryEntry(filePosition uint64, maxRedirects uint8) DirectoryEntry {
+func direntError(filePosition uint64, err error) error {
+	return readError("read directory entry", int64(filePosition), err, ErrTruncatedEntry)
+}
+
+func (z *File) readDirectoryEntry(filePosition uint64) (DirectoryEntry, error) {
+	return z.decodeDirectoryEntry(z.dataReaderAt(int64(filePosition)), filePosition)
+}
+
+// decodeDirectoryEntry reads the Directory Entry at the file position from r,
+// which must be positioned at the start of the Directory Entry.
+func (z *File) decodeDirectoryEntry(r dataReader, filePosition uint64) (DirectoryEntry, error) {
 	var result = DirectoryEntry{}
-	seek(z.f, int64(filePosition))
-	result.mimetype = Mimetype(readUint16(z.f))
-	result.parameterLen = readUint8(z.f)
-	result.namespace = Namespace(readUint8(z.f))
-	result.revision = readUint32(z.f)
+	var mimetype, mimetypeErr = readUint16(r)
+	if mimetypeErr != nil {
+		return result, direntError(filePosition, mimetypeErr)
+	}
+	result.mimetype = Mimetype(mimetype)
+	var err error
+	if result.parameterLen, err = readUint8(r); err != nil {
+		return result, direntError(filePosition, err)
+	}
+	var namespace uint8
+	if namespace, err = readUint8(r); err != nil {
+		return result, direntError(filePosition, err)
+	}
+	result.namespace = Namespace(namespace)
+	if result.revision, err = readUint32(r); err != nil {
+		return result, direntError(filePosition, err)
+	}
 	switch result.mimetype {
 	case MimetypeDeletedEntry, MimetypeLinkTarget:
 		// no extra fields here
 	case MimetypeRedirectEntry:
-		result.blobNumberOrRedirectIndex = readUint32(z.f) // redirectIndex
-		if maxRedirects > 0 {
-			return z.readDirectoryEntry(z.urlPointerAtPos(result.RedirectIndex()), maxRedirects-1)
+		if result.blobNumberOrRedirectIndex, err = readUint32(r); err != nil { // redirectIndex
+			return result, direntError(filePosition, err)
+		}
+		if result.RedirectIndex() >= z.header.articleCount {
+			return result, &ReadError{Op: "read directory entry", Offset: int64(filePosition), Err: ErrInvalidPointer}
 		}
 	default:
 		// Mimetype: ArticleEntry
-		result.clusterNumber = readUint32(z.f)
-		result.blobNumberOrRedirectIndex = readUint32(z.f) // blobNumber
-	}
-	result.url = readNullTerminatedSlice(z.f)
-	result.title = readNullTerminatedSlice(z.f)
-	//if result.parameterLen > 0 {
-	//var buf, readErr = readSlice(z.f, int(result.parameterLen))
-	//if readErr != nil {
-	//	log.Printf("Error reading parameter data of length %d: %s\n", result.parameterLen, readErr)
-	//} else {
-	//	result.parameter = string(buf)
-	//}
-	//}
-	return result
+		if result.clusterNumber, err = readUint32(r); err != nil {
+			return result, direntError(filePosition, err)
+		}
+		if result.blobNumberOrRedirectIndex, err = readUint32(r); err != nil { // blobNumber
+			return result, direntError(filePosition, err)
+		}
+	}
+	if result.url, err = readNullTerminatedSlice(r); err != nil {
+		return result, direntError(filePosition, err)
+	}
+	if result.title, err = readNullTerminatedSlice(r); err != nil {
+		return result, direntError(filePosition, err)
+	}
+	if result.parameterLen > 0 {
+		result.parameter = make([]byte, result.parameterLen)
+		if _, err = io.ReadFull(r, result.parameter); err != nil {
+			return result, direntError(filePosition, err)
+		}
+	}
+	return result, nil
+}
+
+// encodedLen returns the size of the Directory Entry in the ZIM file.
+func (e *DirectoryEntry) encodedLen() int64 {
+	var n = int64(2 + 1 + 1 + 4) // mimetype, parameter length, namespace, revision
+	switch e.mimetype {
+	case MimetypeDeletedEntry, MimetypeLinkTarget:
+	case MimetypeRedirectEntry:
+		n += 4 // redirect index
+	default:
+		n += 4 + 4 // cluster number, blob number
+	}
+	return n + int64(len(e.url)+1+len(e.title)+1+len(e.parameter))
+}
+
+// entryAtPointer reads the Directory Entry of the pointer returned by pointerAtPosition.
+func (z *File) entryAtPointer(pointer uint64, pointerErr error) (DirectoryEntry, error) {
+	if pointerErr != nil {
+		return DirectoryEntry{}, pointerErr
+	}
+	return z.readDirectoryEntry(pointer)
 }
 
 // EntryAtURLPosition returns the Directory Entry
 // at the position as defined in the ordered URL pointerlist.
-// If 0 >= position < z.ArticleCount() the returned error is nil.
+// If 0 >= position < z.ArticleCount() and the Directory Entry
+// could be read the returned error is nil.
 // Redirects are not followed automatically.
 func (z *File) EntryAtURLPosition(position uint32) (DirectoryEntry, error) {
-	if position >= z.header.articleCount {
-		return DirectoryEntry{}, errors.New("zim: position out of range")
-	}
-	return z.readDirectoryEntry(z.urlPointerAtPos(position), 0), nil
+	var pointer, pointerErr = z.urlPointerAtPos(position)
+	return z.entryAtPointer(pointer, pointerErr)
 }
 
 // EntryAtTitlePosition returns the Directory Entry
 // at the position as defined in the ordered title pointerlist.
-// If 0 >= position < z.ArticleCount() the returned error is nil.
+// If 0 >= position < z.ArticleCount() and the Directory Entry
+// could be read the returned error is nil.
 // Redirects are not followed automatically.
 func (z *File) EntryAtTitlePosition(position uint32) (DirectoryEntry, error) {
-	if position >= z.header.articleCount {
-		return DirectoryEntry{}, errors.New("zim: position out of range")
-	}
-	return z.readDirectoryEntry(z.titlePointerAtPos(position), 0), nil
+	var pointer, pointerErr = z.titlePointerAtPos(position)
+	return z.entryAtPointer(pointer, pointerErr)
 }
 
-// IsArticle checks whether the Directory Entry is an Article
+// IsArticle checks whether the Directory Entry is an Article.
+// Files with the new namespace scheme don't separate articles from other contents,
+// so every content entry in NamespaceContent is regarded as an Article.
 func (e *DirectoryEntry) IsArticle() bool {
-	return e.namespace == NamespaceArticles && e.mimetype < MimetypeDeletedEntry
+	return (e.namespace == NamespaceArticles || e.namespace == NamespaceContent) && e.mimetype < MimetypeDeletedEntry
 }
 
 // IsRedirect checks whether the Directory Entry is a Redirect
@@ -143,49 +202,85 @@ func (e *DirectoryEntry) IsDeletedEntry() bool {
 	return e.mimetype == MimetypeDeletedEntry
 }
 
-// FollowRedirect returns the target Directory Entry of the given Redirect Entry
+// FollowRedirect returns the target Directory Entry of the given Redirect Entry.
+// Redirect chains are followed up to DefaultRedirectDepth redirects, see ResolveRedirect.
 func (z *File) FollowRedirect(redirectEntry *DirectoryEntry) (DirectoryEntry, error) {
 	if !redirectEntry.IsRedirect() {
-		return *redirectEntry, errors.New("zim: Directory Entry is not a Redirect Entry")
+		return *redirectEntry, ErrNotRedirect
+	}
+	var target, _, err = z.ResolveRedirect(redirectEntry, DefaultRedirectDepth)
+	return target, err
+}
+
+// entryAtURLPositionResolved returns the Directory Entry at the position in the URL pointerlist
+// and follows its redirects up to DefaultRedirectDepth redirects.
+func (z *File) entryAtURLPositionResolved(position uint32) (DirectoryEntry, error) {
+	var entry, err = z.EntryAtURLPosition(position)
+	if err != nil {
+		return entry, err
 	}
-	return z.readDirectoryEntry(z.urlPointerAtPos(redirectEntry.RedirectIndex()), 4), nil
+	entry, _, err = z.ResolveRedirect(&entry, DefaultRedirectDepth)
+	return entry, err
 }
 
-// MainPage returns the Directory Entry for the MainPage of the ZIM file
+// MainPage returns the Directory Entry for the MainPage of the ZIM file.
+// If the header specifies no MainPage, files with the new namespace scheme
+// use the target of the redirect W/mainPage.
 func (z *File) MainPage() (DirectoryEntry, error) {
 	if z.header.mainPage == NoMainPage {
+		if z.HasNewNamespaceScheme() {
+			var entry, _, err = z.EntryWithURL(NamespaceWellKnown, []byte("mainPage"))
+			if err == nil && entry.IsRedirect() {
+				return z.FollowRedirect(&entry)
+			}
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return entry, err
+			}
+		}
 		return DirectoryEntry{
 			namespace: NamespaceArticles,
 			url:       []byte("index.html"),
-		}, errors.New("zim: no main page specified in ZIM file")
+		}, ErrNoMainPage
 	}
-	return z.readDirectoryEntry(z.urlPointerAtPos(z.header.mainPage), 4), nil
+	return z.entryAtURLPositionResolved(z.header.mainPage)
 }
 
 // LayoutPage returns the Directory Entry for the LayoutPage of the ZIM file
 func (z *File) LayoutPage() (DirectoryEntry, error) {
 	if z.header.layoutPage == NoLayoutPage {
 		mainPage, _ := z.MainPage()
-		return mainPage, errors.New("zim: no layout page specified in ZIM file")
+		return mainPage, ErrNoLayoutPage
 	}
-	return z.readDirectoryEntry(z.urlPointerAtPos(z.header.layoutPage), 4), nil
+	return z.entryAtURLPositionResolved(z.header.layoutPage)
+}
+
+// faviconPaths are the paths of the favicon in the order they are tried.
+// The 48x48 illustration of the metadata is used by files with the new namespace scheme.
+var faviconPaths = []struct {
+	namespace Namespace
+	url       string
+}{
+	{NamespaceZimMetadata, "Illustration_48x48@1"},
+	{NamespaceLayout, "favicon"},
+	{NamespaceLayout, "favicon.png"},
+	{NamespaceImagesFiles, "favicon"},
+	{NamespaceImagesFiles, "favicon.png"},
 }
 
 // Favicon returns the Directory Entry for the Favicon of the ZIM file
 func (z *File) Favicon() (entry DirectoryEntry, err error) {
-	for _, namespace := range [2]Namespace{NamespaceLayout, NamespaceImagesFiles} {
-		var found bool
-		entry, _, found = z.EntryWithURL(namespace, []byte("favicon"))
-		if !found {
-			entry, _, found = z.EntryWithURL(namespace, []byte("favicon.png"))
-		}
-		if found {
+	for _, path := range faviconPaths {
+		entry, _, err = z.EntryWithURL(path.namespace, []byte(path.url))
+		if err == nil {
 			if entry.IsRedirect() {
 				entry, err = z.FollowRedirect(&entry)
 			}
 			return
 		}
+		if !errors.Is(err, ErrNotFound) {
+			return
+		}
 	}
-	err = errors.New("zim: favicon not found")
+	err = ErrNoFavicon
 	return
 }
@@ -2,31 +2,35 @@ package zim
 
 import (
 	"bytes"
+	"errors"
 	"hash/fnv"
 )
 
 const defaultLimitEntries = 100
 
 // EntryWithURL searches for the Directory Entry with the exact URL.
-// If the Directory Entry was found, found is set to true and
+// If the Directory Entry was found, the returned error is nil and
 // the returned position will be the position in the URL pointerlist.
 // This can be used to iterate over the next n Directory Entries using
 // z.EntryAtURLPosition(position+n).
+// If no Directory Entry has the URL, the returned error is ErrNotFound.
 func (z *File) EntryWithURL(namespace Namespace, url []byte) (
-	entry DirectoryEntry, urlPosition uint32, found bool) {
+	entry DirectoryEntry, urlPosition uint32, err error) {
 	// more optimized version of entryWithPrefix
 	var firstURLPosition int64
 	var currentURLPos int64
-	var lastURLPosition = int64(z.header.articleCount - 1)
+	var lastURLPosition = int64(z.header.articleCount) - 1
 	for firstURLPosition <= lastURLPosition {
 		currentURLPos = (firstURLPosition + lastURLPosition) >> 1
-		entry = z.readDirectoryEntry(z.urlPointerAtPos(uint32(currentURLPos)), 0)
+		if entry, err = z.EntryAtURLPosition(uint32(currentURLPos)); err != nil {
+			return
+		}
 		var c = cmpNs(entry.namespace, namespace)
 		if c == 0 {
 			c = bytes.Compare(entry.url, url)
 			if c == 0 {
-				found = true
-				break
+				urlPosition = uint32(currentURLPos)
+				return
 			}
 		}
 		if c < 0 {
@@ -36,73 +40,117 @@ func (z *File) EntryWithURL(namespace Namespace, url []byte) (
 		}
 	}
 	urlPosition = uint32(currentURLPos)
+	err = ErrNotFound
+	return
+}
+
+// contentNamespaces are the namespaces searched by EntryWithPath
+// in files with the old namespace scheme.
+var contentNamespaces = [...]Namespace{NamespaceArticles, NamespaceImagesFiles, NamespaceImagesText, NamespaceLayout}
+
+// EntryWithPath searches for the Directory Entry of the content at the path, the same way
+// for files with the old and the new namespace scheme (see z.HasNewNamespaceScheme()).
+// Files with the new namespace scheme store all contents in NamespaceContent.
+// In older files the path may start with a namespace, like "I/image.png",
+// otherwise the namespaces of articles, images and layout are searched in this order.
+// The returned position is the position in the URL pointerlist.
+// If no Directory Entry has the path, the returned error is ErrNotFound.
+func (z *File) EntryWithPath(path string) (entry DirectoryEntry, urlPosition uint32, err error) {
+	if z.HasNewNamespaceScheme() {
+		return z.EntryWithURL(NamespaceContent, []byte(path))
+	}
+	if len(path) > 2 && path[1] == '/' {
+		entry, urlPosition, err = z.EntryWithURL(Namespace(path[0]), []byte(path[2:]))
+		if !errors.Is(err, ErrNotFound) {
+			return
+		}
+	}
+	for _, namespace := range contentNamespaces {
+		entry, urlPosition, err = z.EntryWithURL(namespace, []byte(path))
+		if !errors.Is(err, ErrNotFound) {
+			return
+		}
+	}
 	return
 }
 
 // EntryWithURLPrefix searches the first Directory Entry in the namespace
-// having the given URL prefix. If it was found, found is set to true and
+// having the given URL prefix. If it was found, the returned error is nil and
 // the returned position will be the position in the URL pointerlist.
 // This can be used to iterate over the next n Directory Entries using
 // z.EntryAtURLPosition(position+n).
+// If no Directory Entry has the prefix, the returned error is ErrNotFound.
 func (z *File) EntryWithURLPrefix(namespace Namespace, prefix []byte) (
-	entry DirectoryEntry, position uint32, found bool) {
-	return z.entryWithPrefix(z.urlPointerAtPos, chooseURL, namespace, prefix)
+	entry DirectoryEntry, position uint32, err error) {
+	return z.entryWithPrefix(z.EntryAtURLPosition, z.header.articleCount, chooseURL, namespace, prefix)
 }
 
 // EntryWithNamespace searches the first Directory Entry in the namespace.
-// If it was found, found is set to true and the returned position will
+// If it was found, the returned error is nil and the returned position will
 // be the position in the URL pointerlist.
 // This can be used to iterate over the next n Directory Entries using
 // z.EntryAtURLPosition(position+n).
+// If the namespace is empty, the returned error is ErrNotFound.
 func (z *File) EntryWithNamespace(namespace Namespace) (
-	entry DirectoryEntry, position uint32, found bool) {
+	entry DirectoryEntry, position uint32, err error) {
 	return z.EntryWithURLPrefix(namespace, nil)
 }
 
 // EntryWithTitlePrefix searches the first Directory Entry in the namespace
-// having the given title prefix. If it was found, found is set to true and
+// having the given title prefix. If it was found, the returned error is nil and
 // the returned position will be the position in the title pointerlist.
 // This can be used to iterate over the next n Directory Entries using
 // z.EntryAtTitlePosition(position+n).
+// If no Directory Entry has the prefix, the returned error is ErrNotFound.
 func (z *File) EntryWithTitlePrefix(namespace Namespace, prefix []byte) (
-	entry DirectoryEntry, position uint32, found bool) {
-	return z.entryWithPrefix(z.titlePointerAtPos, chooseTitle, namespace, prefix)
+	entry DirectoryEntry, position uint32, err error) {
+	return z.entryWithPrefix(z.EntryAtTitlePosition, z.header.articleCount, chooseTitle, namespace, prefix)
 }
 
 // EntriesWithURLPrefix returns all Directory Entries in the Namespace
 // that have the same URL prefix like the given.
 // When the Limit is set to <= 0 it gets the default value 100.
-func (z *File) EntriesWithURLPrefix(namespace Namespace, prefix []byte, limit int) []DirectoryEntry {
-	return z.entriesWithPrefix(chooseURL, z.urlPointerAtPos, namespace, prefix, limit)
+// The error is only set when reading the ZIM file failed.
+func (z *File) EntriesWithURLPrefix(namespace Namespace, prefix []byte, limit int) ([]DirectoryEntry, error) {
+	return z.entriesWithPrefix(chooseURL, z.EntryAtURLPosition, z.header.articleCount, namespace, prefix, limit)
 }
 
 // EntriesWithNamespace returns the first n Directory Entries in the Namespace
 // where n <= limit.
 // When the Limit is set to <= 0 it gets the default value 100.
-func (z *File) EntriesWithNamespace(namespace Namespace, limit int) []DirectoryEntry {
+// The error is only set when reading the ZIM file failed.
+func (z *File) EntriesWithNamespace(namespace Namespace, limit int) ([]DirectoryEntry, error) {
 	return z.EntriesWithURLPrefix(namespace, nil, limit)
 }
 
 // EntriesWithTitlePrefix returns all Directory Entries in the Namespace
 // that have the same Title prefix like the given.
 // When the Limit is set to <= 0 it gets the default value 100.
-func (z *File) EntriesWithTitlePrefix(namespace Namespace, prefix []byte, limit int) []DirectoryEntry {
-	return z.entriesWithPrefix(chooseTitle, z.titlePointerAtPos, namespace, prefix, limit)
+// The error is only set when reading the ZIM file failed.
+func (z *File) EntriesWithTitlePrefix(namespace Namespace, prefix []byte, limit int) ([]DirectoryEntry, error) {
+	return z.entriesWithPrefix(chooseTitle, z.EntryAtTitlePosition, z.header.articleCount, namespace, prefix, limit)
 }
 
 // EntriesWithSimilarity returns Directory Entries in the Namespace
 // that have a similar URL prefix or Title prefix to the given one.
 // When the Limit is set to <= 0 it takes the default value 100.
-func (z *File) EntriesWithSimilarity(namespace Namespace, prefix []byte, limit int) []DirectoryEntry {
+// The error is only set when reading the ZIM file failed.
+func (z *File) EntriesWithSimilarity(namespace Namespace, prefix []byte, limit int) ([]DirectoryEntry, error) {
 	const maxLengthDifference = 15
+	if limit <= 0 {
+		limit = defaultLimitEntries
+	}
 	type wasSuggested = struct{}
 	var alreadySuggested = make(map[uint32]wasSuggested, limit)
 	var suggestions = make([]DirectoryEntry, 0, limit)
 	for i := 0; i < maxLengthDifference; i++ {
 
-		for _, prefixFunc := range [2]func(Namespace, []byte, int) []DirectoryEntry{
+		for _, prefixFunc := range [2]func(Namespace, []byte, int) ([]DirectoryEntry, error){
 			z.EntriesWithURLPrefix, z.EntriesWithTitlePrefix} {
-			var nextSuggestions = prefixFunc(namespace, prefix, limit)
+			var nextSuggestions, err = prefixFunc(namespace, prefix, limit)
+			if err != nil {
+				return suggestions, err
+			}
 			for _, suggestion := range nextSuggestions {
 				var key = hash(suggestion.url)
 				var _, suggestedBefore = alreadySuggested[key]
@@ -110,7 +158,7 @@ func (z *File) EntriesWithSimilarity(namespace Namespace, prefix []byte, limit i
 					suggestions = append(suggestions, suggestion)
 					alreadySuggested[key] = wasSuggested{}
 					if len(suggestions) >= limit {
-						return suggestions
+						return suggestions, nil
 					}
 				}
 			}
@@ -118,19 +166,21 @@ func (z *File) EntriesWithSimilarity(namespace Namespace, prefix []byte, limit i
 		}
 
 		if len(prefix) == 0 {
-			return suggestions
+			return suggestions, nil
 		}
 
 		prefix = prefix[:len(prefix)-1]
 	}
 
-	return suggestions
+	return suggestions, nil
 }
 
 func chooseTitle(entry *DirectoryEntry) []byte { return entry.title }
 
 func chooseURL(entry *DirectoryEntry) []byte { return entry.url }
 
+func chooseTitleOrURL(entry *DirectoryEntry) []byte { return entry.Title() }
+
 func hash(data []byte) uint32 {
 	h := fnv.New32a()
 	h.Write(data)
@@ -154,33 +204,40 @@ func cmpPrefix(s, prefix []byte) int {
 	return bytes.Compare(s, prefix)
 }
 
+// entryWithPrefix searches the first of count Directory Entries returned by entryAtPosition,
+// which are ordered by namespace and the field returned by chooseField.
 func (z *File) entryWithPrefix(
-	pointerAtPosition func(uint32) uint64,
+	entryAtPosition func(uint32) (DirectoryEntry, error),
+	count uint32,
 	chooseField func(entry *DirectoryEntry) []byte,
 	namespace Namespace,
 	prefix []byte) (
-	entry DirectoryEntry, position uint32, found bool) {
+	entry DirectoryEntry, position uint32, err error) {
 	var firstPosition int64
 	var currentPosition int64
-	var lastPosition = int64(z.header.articleCount - 1)
+	var lastPosition = int64(count) - 1
 	for firstPosition <= lastPosition {
 		currentPosition = (firstPosition + lastPosition) >> 1
-		entry = z.readDirectoryEntry(pointerAtPosition(uint32(currentPosition)), 0)
+		if entry, err = entryAtPosition(uint32(currentPosition)); err != nil {
+			return
+		}
 		var c = cmpNs(entry.namespace, namespace)
 		if c == 0 {
 			c = cmpPrefix(chooseField(&entry), prefix)
 			if c == 0 {
 				// we found an entry with the given prefix
+				position = uint32(currentPosition)
 				if currentPosition == 0 {
 					// already lowest position
-					found = true
-					break
+					return
+				}
+				var prevEntry DirectoryEntry
+				if prevEntry, err = entryAtPosition(uint32(currentPosition - 1)); err != nil {
+					return
 				}
-				var prevEntry = z.readDirectoryEntry(pointerAtPosition(uint32(currentPosition-1)), 0)
 				if prevEntry.namespace != namespace || !bytes.HasPrefix(chooseField(&prevEntry), prefix) {
 					// we found the lowest position
-					found = true
-					break
+					return
 				}
 				// the entry below also has the prefix, but maybe much more entries have it too...
 				c = 1 // so the current entry is greater
@@ -193,38 +250,71 @@ func (z *File) entryWithPrefix(
 		}
 	}
 	position = uint32(currentPosition)
+	err = ErrNotFound
 	return
 }
 
 func (z *File) entriesWithPrefix(
 	chooseField func(*DirectoryEntry) []byte,
-	pointerAtPosition func(uint32) uint64,
+	entryAtPosition func(uint32) (DirectoryEntry, error),
+	count uint32,
 	namespace Namespace,
 	prefix []byte,
-	limit int) []DirectoryEntry {
-	var entry, position, found = z.entryWithPrefix(pointerAtPosition, chooseField, namespace, prefix)
-	var result []DirectoryEntry
-	if found {
-		if limit <= 0 {
-			limit = defaultLimitEntries
+	limit int) ([]DirectoryEntry, error) {
+	var entry, position, err = z.entryWithPrefix(entryAtPosition, count, chooseField, namespace, prefix)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultLimitEntries
+	}
+	var capacity = defaultLimitEntries
+	if limit <= defaultLimitEntries {
+		capacity = limit
+	}
+	var result = make([]DirectoryEntry, 0, capacity)
+	result = append(result, entry)
+	var entriesAdded = 1
+	var lastPosition = count - 1
+	for entriesAdded < limit && position < lastPosition {
+		position++
+		var nextEntry, nextEntryErr = entryAtPosition(position)
+		if nextEntryErr != nil {
+			return result, nextEntryErr
 		}
-		var capacity = defaultLimitEntries
-		if limit <= defaultLimitEntries {
-			capacity = limit
+		if nextEntry.namespace != namespace || !bytes.HasPrefix(chooseField(&nextEntry), prefix) {
+			break
 		}
-		result = make([]DirectoryEntry, 0, capacity)
-		result = append(result, entry)
-		var entriesAdded = 1
-		var lastPosition = z.header.articleCount - 1
-		for entriesAdded < limit && position < lastPosition {
-			position++
-			var nextEntry = z.readDirectoryEntry(pointerAtPosition(position), 0)
-			if !bytes.HasPrefix(chooseField(&nextEntry), prefix) {
-				break
-			}
-			result = append(result, nextEntry)
-			entriesAdded++
+		result = append(result, nextEntry)
+		entriesAdded++
+	}
+	return result, nil
+}
+
+// urlLowerBound returns the first position in the URL pointerlist,
+// whose Directory Entry is not less than the given namespace and URL.
+// If all Directory Entries are less, z.ArticleCount() is returned.
+func (z *File) urlLowerBound(namespace Namespace, url []byte) (uint32, error) {
+	var firstPosition int64
+	var lastPosition = int64(z.header.articleCount)
+	for firstPosition < lastPosition {
+		var currentPosition = (firstPosition + lastPosition) >> 1
+		var entry, err = z.EntryAtURLPosition(uint32(currentPosition))
+		if err != nil {
+			return 0, err
+		}
+		var c = cmpNs(entry.namespace, namespace)
+		if c == 0 {
+			c = bytes.Compare(entry.url, url)
+		}
+		if c < 0 {
+			firstPosition = currentPosition + 1
+		} else {
+			lastPosition = currentPosition
 		}
 	}
-	return result
+	return uint32(firstPosition), nil
 }
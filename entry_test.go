@@ -2,7 +2,10 @@ package zim
 
 import (
 	"bytes"
+	"io/ioutil"
 	"log"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -70,9 +73,9 @@ func TestFavicon(t *testing.T) {
 
 func TestRedirects(t *testing.T) {
 	var redirectEntryURL = []byte("Orbite_heliosynchrone.html")
-	var redirectEntry, _, found = z.EntryWithURL(NamespaceArticles, redirectEntryURL)
-	if !found {
-		t.Errorf("Directory Entry with URL `%s` not found.", redirectEntryURL)
+	var redirectEntry, _, redirectEntryErr = z.EntryWithURL(NamespaceArticles, redirectEntryURL)
+	if redirectEntryErr != nil {
+		t.Errorf("Directory Entry with URL `%s` not found: %s", redirectEntryURL, redirectEntryErr)
 	}
 	if !redirectEntry.IsRedirect() {
 		t.Errorf("Directory Entry with URL `%s` not detected as Redirect Entry.", redirectEntryURL)
@@ -162,8 +165,8 @@ func TestEntryLookupAndPositions(t *testing.T) {
 	}
 
 	for _, entry := range entries {
-		entry1, urlPosition1, found1 := z.EntryWithURL(entry.Namespace(), entry.URL())
-		if !found1 {
+		entry1, urlPosition1, err1 := z.EntryWithURL(entry.Namespace(), entry.URL())
+		if err1 != nil {
 			t.Errorf("Entry not found by URL lookup: %s\n", entry1.String())
 		}
 
@@ -175,8 +178,8 @@ func TestEntryLookupAndPositions(t *testing.T) {
 			t.Errorf("Wrong entry found by URL lookup for %s: %s\n", entry.String(), entry1.String())
 		}
 
-		entry2, urlPosition2, found2 := z.EntryWithURLPrefix(entry.Namespace(), entry.URL())
-		if !found2 {
+		entry2, urlPosition2, err2 := z.EntryWithURLPrefix(entry.Namespace(), entry.URL())
+		if err2 != nil {
 			t.Errorf("Entry not found by URL-Prefix lookup: %s\n", entry2.String())
 		}
 
@@ -217,8 +220,8 @@ func TestEntryInNamespace(t *testing.T) {
 		NamespaceImagesFiles,
 		NamespaceZimMetadata,
 	} {
-		entry, urlPosition, found := z.EntryWithNamespace(namespace)
-		if !found {
+		entry, urlPosition, err := z.EntryWithNamespace(namespace)
+		if err != nil {
 			t.Errorf("z.EntryWithNamespace() couldn't find namespace %s\n", namespace)
 		}
 		if entry.Namespace() != namespace {
@@ -235,3 +238,51 @@ func TestEntryInNamespace(t *testing.T) {
 		}
 	}
 }
+
+func TestEntryParameters(t *testing.T) {
+	const filenameParameters = "parameters.zim"
+	var tests = []struct {
+		url       string
+		parameter string
+	}{
+		{"index.html", "\x00\x01\x02\xff"},
+		{"long.html", strings.Repeat("0123456789abcdef", 15) + "0123456789abcde"},
+		{"plain.html", ""},
+		{"Redirect.html", "r"},
+	}
+	for name, open := range map[string]func(string) (*File, error){"Open": Open, "OpenMmap": OpenMmap} {
+		var zp, openErr = open(path.Join("testdata", filenameParameters))
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		if title := zp.Title(); title != "Parameter test" {
+			t.Errorf("%s: zp.Title() was `%s`; want `%s`", name, title, "Parameter test")
+		}
+		for _, test := range tests {
+			var entry, _, entryErr = zp.EntryWithURL(NamespaceArticles, []byte(test.url))
+			if entryErr != nil {
+				t.Errorf("%s: %s: %s", name, test.url, entryErr)
+				continue
+			}
+			if parameter := entry.Parameter(); string(parameter) != test.parameter {
+				t.Errorf("%s: %s has parameter %q; want %q", name, test.url, parameter, test.parameter)
+			}
+			if entry.IsRedirect() {
+				continue
+			}
+			var reader, _, readerErr = zp.BlobReader(&entry)
+			if readerErr != nil {
+				t.Fatal(readerErr)
+			}
+			if data, _ := ioutil.ReadAll(reader); !bytes.Contains(data, []byte(strings.TrimSuffix(test.url, ".html"))) {
+				t.Errorf("%s: content of %s is `%s`", name, test.url, data)
+			}
+		}
+		zp.Close()
+	}
+
+	var mainPage, _ = z.MainPage()
+	if parameter := mainPage.Parameter(); len(parameter) != 0 {
+		t.Errorf("mainPage.Parameter() returned %q; want no parameters", parameter)
+	}
+}
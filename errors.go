@@ -0,0 +1,65 @@
+package zim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned when reading a ZIM file.
+// They can be checked with errors.Is, also if they are wrapped in a ReadError.
+var (
+	ErrInvalidHeader          = errors.New("zim: invalid ZIM header")
+	ErrUnsupportedVersion     = errors.New("zim: version currently not supported")
+	ErrOutOfRange             = errors.New("zim: position out of range")
+	ErrInvalidPointer         = errors.New("zim: pointer out of range")
+	ErrTruncatedEntry         = errors.New("zim: truncated directory entry")
+	ErrInvalidCluster         = errors.New("zim: invalid cluster")
+	ErrUnsupportedCompression = errors.New("zim: unsupported cluster compression")
+	ErrInvalidBlob            = errors.New("zim: invalid blob position")
+	ErrInvalidMimetypeList    = errors.New("zim: invalid mimetype list")
+	ErrInvalidMimetype        = errors.New("zim: mimetype not in mimetype list")
+	ErrInvalidListing         = errors.New("zim: invalid front article listing")
+	ErrChecksumMismatch       = errors.New("zim: checksum mismatched")
+	ErrNotFound               = errors.New("zim: Directory Entry not found")
+	ErrNotRedirect            = errors.New("zim: Directory Entry is not a Redirect Entry")
+	ErrRedirectTooDeep        = errors.New("zim: too many redirects")
+	ErrRedirectCycle          = errors.New("zim: redirect cycle")
+	ErrNoMainPage             = errors.New("zim: no main page specified in ZIM file")
+	ErrNoLayoutPage           = errors.New("zim: no layout page specified in ZIM file")
+	ErrNoFavicon              = errors.New("zim: favicon not found")
+	ErrNoRangeSupport         = errors.New("zim: HTTP server doesn't support range requests")
+)
+
+// Errors returned when writing a ZIM file.
+var (
+	ErrInvalidEntry   = errors.New("zim: invalid Directory Entry")
+	ErrDuplicateEntry = errors.New("zim: duplicate Directory Entry")
+	ErrWriterClosed   = errors.New("zim: writer already closed")
+	ErrEntryMismatch  = errors.New("zim: Directory Entries of the files differ")
+)
+
+// ReadError records a failed read of a part of the ZIM file and the file position of that part.
+// Err is either one of the sentinel errors of this package, when the data is corrupt or truncated,
+// or the error returned by the underlying reader.
+type ReadError struct {
+	Op     string // the part of the ZIM file that was read, e.g. "read directory entry"
+	Offset int64  // file position of the part
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %s", e.Op, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ReadError) Unwrap() error { return e.Err }
+
+// readError wraps err in a ReadError. A short read means the data is truncated,
+// in that case err is replaced by the given sentinel error.
+func readError(op string, offset int64, err, truncated error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = truncated
+	}
+	return &ReadError{Op: op, Offset: offset, Err: err}
+}
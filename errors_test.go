@@ -0,0 +1,134 @@
+package zim
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+// corruptedTestfile writes a copy of the mixed compression test file
+// changed by corrupt into a temporary directory and returns its path.
+func corruptedTestfile(t *testing.T, corrupt func(data []byte) []byte) string {
+	var data, readErr = ioutil.ReadFile(path.Join("testdata", filenameMixedCompression))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	var filename = path.Join(t.TempDir(), filenameMixedCompression)
+	if writeErr := ioutil.WriteFile(filename, corrupt(data), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	return filename
+}
+
+func TestOpenCorruptHeader(t *testing.T) {
+	var tests = []struct {
+		name     string
+		corrupt  func(data []byte) []byte
+		expected error
+	}{
+		{"truncated header", func(data []byte) []byte { return data[:50] }, ErrInvalidHeader},
+		{"magic number", func(data []byte) []byte { data[0]++; return data }, ErrInvalidHeader},
+		{"version", func(data []byte) []byte { data[4] = 7; return data }, ErrUnsupportedVersion},
+		{"truncated file", func(data []byte) []byte { return data[:len(data)-20] }, ErrInvalidHeader},
+		{"URL pointer list", func(data []byte) []byte {
+			binary.LittleEndian.PutUint64(data[32:], uint64(len(data)))
+			return data
+		}, ErrInvalidHeader},
+	}
+	for _, test := range tests {
+		var zc, err = Open(corruptedTestfile(t, test.corrupt))
+		if err == nil {
+			zc.Close()
+			t.Errorf("%s: Open() of corrupt file succeeded", test.name)
+			continue
+		}
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: Open() returned `%s`; want `%s`", test.name, err, test.expected)
+		}
+		var readErr *ReadError
+		if !errors.As(err, &readErr) || readErr.Op != "read header" {
+			t.Errorf("%s: Open() returned `%s`; want a ReadError of the header", test.name, err)
+		}
+	}
+}
+
+func TestCorruptDirectoryEntries(t *testing.T) {
+	// position 2 in the URL pointerlist is A/index.html,
+	// which isn't needed to read the metadata in Open
+	const position = 2
+	var corruptPointer = func(pointer func(data []byte) uint64) func(data []byte) []byte {
+		return func(data []byte) []byte {
+			var urlPtrPos = binary.LittleEndian.Uint64(data[32:])
+			binary.LittleEndian.PutUint64(data[urlPtrPos+8*position:], pointer(data))
+			return data
+		}
+	}
+	var tests = []struct {
+		name     string
+		corrupt  func(data []byte) []byte
+		expected error
+	}{
+		{"pointer after checksum", corruptPointer(func(data []byte) uint64 { return uint64(len(data)) }), ErrInvalidPointer},
+		{"truncated entry", corruptPointer(func(data []byte) uint64 { return binary.LittleEndian.Uint64(data[72:]) - 1 }), ErrTruncatedEntry},
+	}
+	for _, test := range tests {
+		var zc, openErr = Open(corruptedTestfile(t, test.corrupt))
+		if openErr != nil {
+			t.Errorf("%s: %s", test.name, openErr)
+			continue
+		}
+		var _, entryErr = zc.EntryAtURLPosition(position)
+		if !errors.Is(entryErr, test.expected) {
+			t.Errorf("%s: zc.EntryAtURLPosition() returned `%v`; want `%s`", test.name, entryErr, test.expected)
+		}
+		var readErr *ReadError
+		if !errors.As(entryErr, &readErr) {
+			t.Errorf("%s: error `%v` is no *ReadError", test.name, entryErr)
+		}
+		var _, _, lookupErr = zc.EntryWithURL(NamespaceArticles, []byte("index.html"))
+		if !errors.Is(lookupErr, test.expected) {
+			t.Errorf("%s: zc.EntryWithURL() returned `%v`; want `%s`", test.name, lookupErr, test.expected)
+		}
+		zc.Close()
+	}
+}
+
+func TestCorruptCluster(t *testing.T) {
+	// cluster 2 stores the zstd compressed blobs
+	const clusterPosition = 2
+	var clusterPointer uint64
+	var filename = corruptedTestfile(t, func(data []byte) []byte {
+		var clusterPtrPos = binary.LittleEndian.Uint64(data[48:])
+		clusterPointer = binary.LittleEndian.Uint64(data[clusterPtrPos+8*clusterPosition:])
+		data[clusterPointer] = 3 // bzip2
+		return data
+	})
+	var zc, openErr = Open(filename)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zc.Close()
+
+	var _, _, blobErr = zc.BlobReaderAt(clusterPosition, 0)
+	if !errors.Is(blobErr, ErrUnsupportedCompression) {
+		t.Errorf("zc.BlobReaderAt() returned `%v`; want `%s`", blobErr, ErrUnsupportedCompression)
+	}
+	var readErr *ReadError
+	if !errors.As(blobErr, &readErr) || readErr.Offset != int64(clusterPointer) {
+		t.Errorf("zc.BlobReaderAt() returned `%v`; want *ReadError at offset %d", blobErr, clusterPointer)
+	}
+	if _, clusterErr := zc.ClusterAt(clusterPosition); !errors.Is(clusterErr, ErrUnsupportedCompression) {
+		t.Errorf("zc.ClusterAt() returned `%v`; want `%s`", clusterErr, ErrUnsupportedCompression)
+	}
+	if _, clusterErr := zc.ClusterAt(zc.ClusterCount()); !errors.Is(clusterErr, ErrOutOfRange) {
+		t.Errorf("zc.ClusterAt() returned `%v`; want `%s`", clusterErr, ErrOutOfRange)
+	}
+	if _, _, blobErr = zc.BlobReaderAt(0, 1000); !errors.Is(blobErr, ErrInvalidBlob) {
+		t.Errorf("zc.BlobReaderAt() returned `%v`; want `%s`", blobErr, ErrInvalidBlob)
+	}
+	if _, _, blobErr = zc.BlobReaderAt(1, 1000); !errors.Is(blobErr, ErrInvalidBlob) {
+		t.Errorf("zc.BlobReaderAt() returned `%v`; want `%s`", blobErr, ErrInvalidBlob)
+	}
+}
@@ -3,9 +3,8 @@ package zim
 
 import (
 	"crypto/md5"
+	"io"
 	"os"
-
-	"github.com/xi2/xz"
 )
 
 // Some useful constants belonging to a ZIM file.
@@ -17,39 +16,86 @@ const (
 
 // File represents a ZIM file and contains the most important
 // information that is retrieved once and used again.
+// All reads use positioned reads without a shared file offset,
+// so a File is safe for concurrent use by multiple goroutines.
 type File struct {
-	f            *os.File
-	xzReader     *xz.Reader
-	header       Header
-	metadata     map[string]string
-	mimetypeList []string
+	r             io.ReaderAt
+	closer        io.Closer // nil if the caller owns r
+	size          int64
+	header        Header
+	metadata      map[string]string
+	metadataKeys  []string
+	mimetypeList  []string
+	clusterCache  clusterCache
+	frontArticles frontArticles
 }
 
 // Open opens the file and checks for a valid ZIM header.
+// Split ZIM files, whose parts are named foo.zimaa, foo.zimab, etc.,
+// are opened as one file with the name of the first part or with foo.zim.
 func Open(filename string) (*File, error) {
+	if basename, split := splitBasename(filename); split {
+		return openSplit(basename)
+	}
 	var f, fileErr = os.Open(filename)
 	if fileErr != nil {
 		return nil, fileErr
 	}
-	var xzReader, xzReaderErr = xz.NewReader(nil, 0)
-	if xzReaderErr != nil {
-		return nil, xzReaderErr
+	var fileInfo, statErr = f.Stat()
+	if statErr != nil {
+		f.Close()
+		return nil, statErr
+	}
+	var result, openErr = OpenReaderAt(f, fileInfo.Size())
+	if openErr != nil {
+		f.Close()
+		return nil, openErr
+	}
+	result.closer = f
+	return result, nil
+}
+
+func openSplit(basename string) (*File, error) {
+	var s, splitErr = openSplitFile(basename)
+	if splitErr != nil {
+		return nil, splitErr
+	}
+	var result, openErr = OpenReaderAt(s, s.Size())
+	if openErr != nil {
+		s.Close()
+		return nil, openErr
 	}
+	result.closer = s
+	return result, nil
+}
+
+// OpenReaderAt reads a ZIM file of the given size from r and checks for a valid ZIM header.
+// This way a ZIM file can be read from memory (bytes.Reader), from a part
+// of a larger file (io.SectionReader) or from any other source that supports
+// positioned reads. r must be safe for concurrent calls of ReadAt, if the File
+// is used by multiple goroutines. Close doesn't close r.
+func OpenReaderAt(r io.ReaderAt, size int64) (*File, error) {
 	var result = &File{
-		f:        f,
-		xzReader: xzReader,
+		r:    r,
+		size: size,
 	}
 	if headerErr := result.readHeader(); headerErr != nil {
 		return nil, headerErr
 	}
-	result.readMimetypeList()
-	result.readMetadata()
+	if mimetypeListErr := result.readMimetypeList(); mimetypeListErr != nil {
+		return nil, mimetypeListErr
+	}
+	if metadataErr := result.readMetadata(); metadataErr != nil {
+		return nil, metadataErr
+	}
 	return result, nil
 }
 
-// Close closes the ZIM file.
+// Close closes the ZIM file, if it was opened by Open.
 func (z *File) Close() {
-	z.f.Close()
+	if z.closer != nil {
+		z.closer.Close()
+	}
 }
 
 // ArticleCount is the total number of articles defined
@@ -2,96 +2,186 @@ package zim
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"io"
-	"os"
 )
 
-func readUint8(f *os.File) uint8 {
+// dataReaderBufferSize is big enough for most Directory Entries and
+// small enough to keep the costs of a random access low.
+const dataReaderBufferSize = 256
+
+func readUint8(r io.Reader) (uint8, error) {
 	const byteLen = 1
 	var arr [byteLen]byte
-	f.Read(arr[:byteLen])
-	return arr[0]
+	var _, err = io.ReadFull(r, arr[:byteLen])
+	return arr[0], err
 }
 
-func readUint16(f *os.File) uint16 {
+func readUint16(r io.Reader) (uint16, error) {
 	const byteLen = 2
 	var arr [byteLen]byte
-	f.Read(arr[:byteLen])
-	return binary.LittleEndian.Uint16(arr[:byteLen])
+	var _, err = io.ReadFull(r, arr[:byteLen])
+	return binary.LittleEndian.Uint16(arr[:byteLen]), err
 }
 
-func readUint32(f *os.File) uint32 {
+func readUint32(r io.Reader) (uint32, error) {
 	const byteLen = 4
 	var arr [byteLen]byte
-	f.Read(arr[:byteLen])
-	return binary.LittleEndian.Uint32(arr[:byteLen])
+	var _, err = io.ReadFull(r, arr[:byteLen])
+	return binary.LittleEndian.Uint32(arr[:byteLen]), err
 }
 
-func readUint32R(r io.Reader) uint32 {
-	const byteLen = 4
+func readUint64(r io.Reader) (uint64, error) {
+	const byteLen = 8
 	var arr [byteLen]byte
-	r.Read(arr[:byteLen])
-	return binary.LittleEndian.Uint32(arr[:byteLen])
+	var _, err = io.ReadFull(r, arr[:byteLen])
+	return binary.LittleEndian.Uint64(arr[:byteLen]), err
 }
 
-func readUint64(f *os.File) uint64 {
-	const byteLen = 8
+func readUint32At(r io.ReaderAt, position int64) (uint32, error) {
+	const byteLen = 4
+	if m, mapped := r.(mappedFile); mapped {
+		if data, ok := m.slice(position, byteLen); ok {
+			return binary.LittleEndian.Uint32(data), nil
+		}
+	}
 	var arr [byteLen]byte
-	f.Read(arr[:byteLen])
-	return binary.LittleEndian.Uint64(arr[:byteLen])
+	var err = readFullAt(r, arr[:byteLen], position)
+	return binary.LittleEndian.Uint32(arr[:byteLen]), err
 }
 
-func readUint64R(r io.Reader) uint64 {
+func readUint64At(r io.ReaderAt, position int64) (uint64, error) {
 	const byteLen = 8
+	if m, mapped := r.(mappedFile); mapped {
+		if data, ok := m.slice(position, byteLen); ok {
+			return binary.LittleEndian.Uint64(data), nil
+		}
+	}
 	var arr [byteLen]byte
-	r.Read(arr[:byteLen])
-	return binary.LittleEndian.Uint64(arr[:byteLen])
+	var err = readFullAt(r, arr[:byteLen], position)
+	return binary.LittleEndian.Uint64(arr[:byteLen]), err
+}
+
+func readSliceAt(r io.ReaderAt, position int64, byteLen int) ([]byte, error) {
+	var buf = make([]byte, byteLen)
+	return buf, readFullAt(r, buf, position)
 }
 
-func seek(f *os.File, position int64) {
-	f.Seek(position, 0)
+// readFullAt reads exactly len(buf) bytes. It returns io.ErrUnexpectedEOF
+// or io.EOF (if nothing was read) when the data ends before.
+func readFullAt(r io.ReaderAt, buf []byte, position int64) error {
+	var n, err = r.ReadAt(buf, position)
+	switch {
+	case n == len(buf):
+		return nil
+	case err == io.EOF && n > 0:
+		return io.ErrUnexpectedEOF
+	case err == nil:
+		return io.ErrUnexpectedEOF
+	default:
+		return err
+	}
 }
 
-func currentPosition(f *os.File) int64 {
-	currentPos, _ := f.Seek(0, 1)
-	return currentPos
+// dataReader is implemented by *bufio.Reader and by *sliceReader for memory mapped files.
+type dataReader interface {
+	io.Reader
+	ReadBytes(delim byte) ([]byte, error)
 }
 
-func readSlice(f *os.File, byteLen int) ([]byte, error) {
-	var buf = make([]byte, byteLen)
-	var _, readErr = f.Read(buf)
-	return buf, readErr
-}
-
-func readNullTerminatedSlice(f *os.File) []byte {
-	const bufferSize = 256
-	var prevFilePosition = currentPosition(f)
-	var bufReader = bufio.NewReaderSize(f, bufferSize)
-	var result, readBufErr = bufReader.ReadBytes(0)
-	var dataLen int
-	if readBufErr == nil {
-		dataLen = len(result) - 1
+// sliceReader reads from a slice. ReadBytes returns parts of the slice without copying them.
+type sliceReader struct {
+	data []byte
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	var n = copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func (r *sliceReader) ReadBytes(delim byte) ([]byte, error) {
+	var i = bytes.IndexByte(r.data, delim)
+	if i < 0 {
+		var rest = r.data
+		r.data = nil
+		return rest, io.EOF
+	}
+	var result = r.data[: i+1 : i+1]
+	r.data = r.data[i+1:]
+	return result, nil
+}
+
+func readNullTerminatedSlice(r dataReader) ([]byte, error) {
+	var result, readBufErr = r.ReadBytes(0)
+	if readBufErr != nil {
+		if readBufErr == io.EOF {
+			readBufErr = io.ErrUnexpectedEOF
+		}
+		return nil, readBufErr
+	}
+	// the capacity is limited, so that an append never writes into a memory mapped file
+	return result[: len(result)-1 : len(result)-1], nil
+}
+
+func readNullTerminatedString(r dataReader) (string, error) {
+	var result, err = readNullTerminatedSlice(r)
+	return string(result), err
+}
+
+// dataReaderAt returns a new buffered reader starting at the given file position.
+// Every call has its own state, so it can be used by concurrent goroutines.
+// Memory mapped files are read without buffering.
+func (z *File) dataReaderAt(position int64) dataReader {
+	if m, mapped := z.r.(mappedFile); mapped {
+		var data, _ = m.slice(position, z.size-position)
+		return &sliceReader{data: data}
 	}
-	seek(f, prevFilePosition+int64(dataLen+1))
-	return result[:dataLen]
+	return bufio.NewReaderSize(io.NewSectionReader(z.r, position, z.size-position), dataReaderBufferSize)
 }
 
-func readNullTerminatedString(f *os.File) string {
-	return string(readNullTerminatedSlice(f))
+// pointerAt reads a pointer to a position in the ZIM file,
+// which must be before the checksum at the end of the file.
+func (z *File) pointerAt(op string, position int64) (uint64, error) {
+	var pointer, err = readUint64At(z.r, position)
+	if err != nil {
+		return 0, readError(op, position, err, ErrInvalidPointer)
+	}
+	if pointer >= z.header.checksumPos {
+		return 0, &ReadError{Op: op, Offset: position, Err: ErrInvalidPointer}
+	}
+	return pointer, nil
 }
 
-func (z *File) urlPointerAtPos(position uint32) uint64 {
-	seek(z.f, int64(z.header.urlPtrPos)+int64(position*8))
-	return readUint64(z.f)
+func (z *File) urlPointerAtPos(position uint32) (uint64, error) {
+	if position >= z.header.articleCount {
+		return 0, ErrOutOfRange
+	}
+	return z.pointerAt("read URL pointer", int64(z.header.urlPtrPos)+int64(position)*8)
 }
 
-func (z *File) titlePointerAtPos(position uint32) uint64 {
-	seek(z.f, int64(z.header.titlePtrPos)+int64(position*4))
-	return z.urlPointerAtPos(readUint32(z.f))
+func (z *File) titlePointerAtPos(position uint32) (uint64, error) {
+	if position >= z.header.articleCount {
+		return 0, ErrOutOfRange
+	}
+	var titlePointerPos = int64(z.header.titlePtrPos) + int64(position)*4
+	var urlPosition, err = readUint32At(z.r, titlePointerPos)
+	if err != nil {
+		return 0, readError("read title pointer", titlePointerPos, err, ErrInvalidPointer)
+	}
+	if urlPosition >= z.header.articleCount {
+		return 0, &ReadError{Op: "read title pointer", Offset: titlePointerPos, Err: ErrInvalidPointer}
+	}
+	return z.urlPointerAtPos(urlPosition)
 }
 
-func (z *File) clusterPointerAtPos(position uint32) uint64 {
-	seek(z.f, int64(z.header.clusterPtrPos)+int64(position*8))
-	return readUint64(z.f)
+func (z *File) clusterPointerAtPos(position uint32) (uint64, error) {
+	if position >= z.header.clusterCount {
+		return 0, ErrOutOfRange
+	}
+	return z.pointerAt("read cluster pointer", int64(z.header.clusterPtrPos)+int64(position)*8)
 }
@@ -1,7 +1,13 @@
 package zim
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"path"
+	"sync"
 	"testing"
 )
 
@@ -47,3 +53,119 @@ func TestUUID(t *testing.T) {
 		t.Errorf("z.UUID().String() was %s; want %s", UUID, expectedUUID)
 	}
 }
+
+func TestConcurrentReads(t *testing.T) {
+	const goroutines = 8
+	var entries []DirectoryEntry
+	for position := uint32(0); position < z.ArticleCount(); position++ {
+		var entry, entryErr = z.EntryAtURLPosition(position)
+		if entryErr != nil {
+			t.Fatal(entryErr)
+		}
+		if !entry.IsRedirect() && !entry.IsLinkTarget() && !entry.IsDeletedEntry() {
+			entries = append(entries, entry)
+		}
+	}
+
+	var clusters = make([]Cluster, z.ClusterCount())
+	for position := range clusters {
+		var clusterErr error
+		if clusters[position], clusterErr = z.ClusterAt(uint32(position)); clusterErr != nil {
+			t.Fatal(clusterErr)
+		}
+	}
+	var expected = make([][]byte, len(entries))
+	for i := range entries {
+		var blobErr error
+		if expected[i], blobErr = clusters[entries[i].ClusterNumber()].BlobAt(entries[i].BlobNumber()); blobErr != nil {
+			t.Fatal(blobErr)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var errs = make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			// every goroutine reads its own share of blobs, since decompressing is slow
+			for index := offset; index < len(entries); index += goroutines {
+				var entry = &entries[index]
+				var found, _, foundErr = z.EntryWithURL(entry.Namespace(), entry.URL())
+				if foundErr != nil || !bytes.Equal(found.URL(), entry.URL()) {
+					errs <- fmt.Errorf("z.EntryWithURL() couldn't find %s", entry.String())
+					return
+				}
+				var blobReader, _, blobReaderErr = z.BlobReader(&found)
+				if blobReaderErr != nil {
+					errs <- blobReaderErr
+					return
+				}
+				var data, readErr = ioutil.ReadAll(blobReader)
+				if readErr != nil {
+					errs <- readErr
+					return
+				}
+				if !bytes.Equal(data, expected[index]) {
+					errs <- fmt.Errorf("concurrent read of %s returned different data", entry.String())
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	var data, readErr = ioutil.ReadFile(path.Join("testdata", filenameTestfile))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	// the ZIM file is embedded in a larger container
+	const padding = 1000
+	var container = append(append(make([]byte, padding), data...), make([]byte, padding)...)
+
+	for name, r := range map[string]io.ReaderAt{
+		"memory":  bytes.NewReader(data),
+		"section": io.NewSectionReader(bytes.NewReader(container), padding, int64(len(data))),
+	} {
+		var zr, openErr = OpenReaderAt(r, int64(len(data)))
+		if openErr != nil {
+			t.Errorf("%s: %s", name, openErr)
+			continue
+		}
+		if zr.Filesize() != z.Filesize() || zr.ArticleCount() != z.ArticleCount() || zr.UUID().String() != z.UUID().String() {
+			t.Errorf("%s: header differs from the header of the ZIM file opened by Open()", name)
+		}
+		if err := zr.ValidateChecksum(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if zr.Title() != z.Title() {
+			t.Errorf("%s: zr.Title() was `%s`; want `%s`", name, zr.Title(), z.Title())
+		}
+		var mainPage, mainPageErr = zr.MainPage()
+		if mainPageErr != nil {
+			t.Errorf("%s: %s", name, mainPageErr)
+			continue
+		}
+		var blobReader, blobSize, blobReaderErr = zr.BlobReader(&mainPage)
+		if blobReaderErr != nil {
+			t.Errorf("%s: %s", name, blobReaderErr)
+			continue
+		}
+		var blob, _ = ioutil.ReadAll(blobReader)
+		const expectedDataLen = 1982
+		if blobSize != expectedDataLen || len(blob) != expectedDataLen {
+			t.Errorf("%s: main page has length %d; want %d", name, len(blob), expectedDataLen)
+		}
+		zr.Close()
+	}
+
+	if _, err := OpenReaderAt(bytes.NewReader(data), int64(len(data))-1); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("OpenReaderAt() with wrong size returned `%v`; want `%s`", err, ErrInvalidHeader)
+	}
+}
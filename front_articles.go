@@ -0,0 +1,171 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// frontArticleListingURL is the URL in NamespaceIndexes of the listing of the front articles
+// in title order. Each front article is stored as its position in the URL pointerlist (uint32).
+const frontArticleListingURL = "listing/titleOrdered/v1"
+
+// frontArticles is the front article listing of a File, which is read on first use.
+type frontArticles struct {
+	mu     sync.Mutex
+	loaded bool // a failed read is not memoized, so the next use reads the listing again
+	// the URL positions of the listing, or nil if the file has no listing
+	listing io.ReaderAt
+	// the title positions of the front articles, if the file has no listing
+	titlePositions []uint32
+	count          uint32
+}
+
+// loadFrontArticles reads the front article listing once it was read successfully.
+func (z *File) loadFrontArticles() error {
+	var f = &z.frontArticles
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.loaded {
+		return nil
+	}
+	if err := z.readFrontArticles(); err != nil {
+		f.listing, f.titlePositions, f.count = nil, nil, 0
+		return err
+	}
+	f.loaded = true
+	return nil
+}
+
+// readFrontArticles reads the front article listing or, if the file has no listing,
+// searches the title pointerlist for the front articles.
+func (z *File) readFrontArticles() error {
+	var f = &z.frontArticles
+	var entry, _, err = z.EntryWithURL(NamespaceIndexes, []byte(frontArticleListingURL))
+	if errors.Is(err, ErrNotFound) {
+		return z.searchFrontArticles()
+	}
+	if err != nil {
+		return err
+	}
+	var reader, size, readerErr = z.BlobReader(&entry)
+	if readerErr != nil {
+		return readerErr
+	}
+	if size%4 != 0 || size/4 > int64(z.header.articleCount) {
+		return fmt.Errorf("%w: size %d", ErrInvalidListing, size)
+	}
+	// uncompressed blobs can be read at the positions directly
+	var listing, isReaderAt = reader.(io.ReaderAt)
+	if !isReaderAt {
+		var data, readErr = ioutil.ReadAll(reader)
+		if readErr != nil {
+			return readErr
+		}
+		listing = bytes.NewReader(data)
+	}
+	f.listing = listing
+	f.count = uint32(size / 4)
+	return nil
+}
+
+// searchFrontArticles collects the front articles of a file without listing,
+// which needs to read every Directory Entry of the title pointerlist.
+func (z *File) searchFrontArticles() error {
+	var f = &z.frontArticles
+	var position uint32
+	for entry, err := range z.EntriesByTitle(0) {
+		if err != nil {
+			return err
+		}
+		if z.isFrontArticle(&entry) {
+			f.titlePositions = append(f.titlePositions, position)
+		}
+		position++
+	}
+	f.count = uint32(len(f.titlePositions))
+	return nil
+}
+
+// isFrontArticle decides for files without listing, if the Directory Entry is a front article:
+// a HTML page in the article or content namespace, which is no redirect.
+func (z *File) isFrontArticle(e *DirectoryEntry) bool {
+	if e.namespace != z.frontArticleNamespace() {
+		return false
+	}
+	var mimetype, err = z.MimetypeOf(e)
+	return err == nil && strings.HasPrefix(mimetype, "text/html")
+}
+
+// frontArticleNamespace is the namespace of all front articles.
+func (z *File) frontArticleNamespace() Namespace {
+	if z.HasNewNamespaceScheme() {
+		return NamespaceContent
+	}
+	return NamespaceArticles
+}
+
+// FrontArticleCount returns the number of front articles. Front articles are the real pages
+// of the ZIM file, without resources like images or stylesheets and without redirects.
+// Newer files list them in X/listing/titleOrdered/v1. For older files the front articles are
+// searched once in the title pointerlist, which reads every Directory Entry of the file.
+func (z *File) FrontArticleCount() (uint32, error) {
+	if err := z.loadFrontArticles(); err != nil {
+		return 0, err
+	}
+	return z.frontArticles.count, nil
+}
+
+// EntryAtFrontArticlePosition returns the Directory Entry of the front article
+// at the position in the title ordered front article listing.
+// If position >= z.FrontArticleCount(), the returned error is ErrOutOfRange.
+func (z *File) EntryAtFrontArticlePosition(position uint32) (DirectoryEntry, error) {
+	if err := z.loadFrontArticles(); err != nil {
+		return DirectoryEntry{}, err
+	}
+	var f = &z.frontArticles
+	if position >= f.count {
+		return DirectoryEntry{}, ErrOutOfRange
+	}
+	if f.listing == nil {
+		return z.EntryAtTitlePosition(f.titlePositions[position])
+	}
+	var urlPosition, err = readUint32At(f.listing, int64(position)*4)
+	if err != nil {
+		return DirectoryEntry{}, readError("read front article listing", int64(position)*4, err, ErrInvalidListing)
+	}
+	if urlPosition >= z.header.articleCount {
+		return DirectoryEntry{}, &ReadError{Op: "read front article listing", Offset: int64(position) * 4, Err: ErrInvalidListing}
+	}
+	return z.EntryAtURLPosition(urlPosition)
+}
+
+// EntryWithFrontArticleTitlePrefix searches the first front article having the given title prefix.
+// If it was found, the returned error is nil and the returned position will be the position
+// in the front article listing. This can be used to iterate over the next n front articles
+// using z.EntryAtFrontArticlePosition(position+n).
+// If no front article has the prefix, the returned error is ErrNotFound.
+func (z *File) EntryWithFrontArticleTitlePrefix(prefix []byte) (
+	entry DirectoryEntry, position uint32, err error) {
+	var count uint32
+	if count, err = z.FrontArticleCount(); err != nil {
+		return
+	}
+	return z.entryWithPrefix(z.EntryAtFrontArticlePosition, count, chooseTitleOrURL, z.frontArticleNamespace(), prefix)
+}
+
+// FrontArticlesWithTitlePrefix returns the front articles that have the same
+// Title prefix like the given, which is useful for an autocompletion.
+// When the Limit is set to <= 0 it gets the default value 100.
+// The error is only set when reading the ZIM file failed.
+func (z *File) FrontArticlesWithTitlePrefix(prefix []byte, limit int) ([]DirectoryEntry, error) {
+	var count, err = z.FrontArticleCount()
+	if err != nil {
+		return nil, err
+	}
+	return z.entriesWithPrefix(chooseTitleOrURL, z.EntryAtFrontArticlePosition, count, z.frontArticleNamespace(), prefix, limit)
+}
@@ -0,0 +1,133 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"testing"
+)
+
+func frontArticleTitles(t *testing.T, zf *File) []string {
+	var count, countErr = zf.FrontArticleCount()
+	if countErr != nil {
+		t.Fatal(countErr)
+	}
+	var titles []string
+	for position := uint32(0); position < count; position++ {
+		var entry, err = zf.EntryAtFrontArticlePosition(position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !entry.IsArticle() || entry.IsRedirect() {
+			t.Errorf("front article %s is no article", entry.String())
+		}
+		titles = append(titles, string(entry.Title()))
+	}
+	if _, err := zf.EntryAtFrontArticlePosition(count); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("zf.EntryAtFrontArticlePosition(%d) returned `%v`; want `%s`", count, err, ErrOutOfRange)
+	}
+	return titles
+}
+
+func TestFrontArticleListing(t *testing.T) {
+	var zn, openErr = Open(path.Join("testdata", filenameNewNamespace))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zn.Close()
+
+	var titles = frontArticleTitles(t, zn)
+	if fmt.Sprint(titles) != "[Foo Food Welcome]" {
+		t.Errorf("front articles are %v; want [Foo Food Welcome]", titles)
+	}
+	if zn.frontArticles.listing == nil {
+		t.Error("front articles weren't read from X/listing/titleOrdered/v1")
+	}
+
+	var entries, entriesErr = zn.FrontArticlesWithTitlePrefix([]byte("Foo"), 0)
+	if entriesErr != nil {
+		t.Fatal(entriesErr)
+	}
+	if len(entries) != 2 || string(entries[0].URL()) != "Foo" || string(entries[1].URL()) != "Food" {
+		t.Errorf("zn.FrontArticlesWithTitlePrefix(\"Foo\") returned %v; want C/Foo and C/Food", entries)
+	}
+	var entry, position, entryErr = zn.EntryWithFrontArticleTitlePrefix([]byte("Wel"))
+	if entryErr != nil || position != 2 || string(entry.URL()) != "index.html" {
+		t.Errorf("zn.EntryWithFrontArticleTitlePrefix(\"Wel\") returned %s at %d, %v; want C/index.html at 2",
+			entry.String(), position, entryErr)
+	}
+	// the redirect Bar and the stylesheet are only in the title pointerlist
+	for _, prefix := range []string{"Bar", "assets"} {
+		if _, _, err := zn.EntryWithFrontArticleTitlePrefix([]byte(prefix)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("zn.EntryWithFrontArticleTitlePrefix(%q) returned `%v`; want `%s`", prefix, err, ErrNotFound)
+		}
+	}
+}
+
+func TestFrontArticlesWithoutListing(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	// notes.txt isn't HTML and Redirect.html is a redirect
+	if titles := frontArticleTitles(t, zm); fmt.Sprint(titles) != "[Index Zstandard]" {
+		t.Errorf("front articles are %v; want [Index Zstandard]", titles)
+	}
+	if _, _, err := zm.EntryWithFrontArticleTitlePrefix([]byte("Redirect")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("zm.EntryWithFrontArticleTitlePrefix(\"Redirect\") returned `%v`; want `%s`", err, ErrNotFound)
+	}
+
+	var titles = frontArticleTitles(t, z)
+	if len(titles) == 0 || len(titles) >= int(z.ArticleCount()) {
+		t.Errorf("z has %d front articles; want between 1 and %d", len(titles), z.ArticleCount()-1)
+	}
+	for i := 1; i < len(titles); i++ {
+		if bytes.Compare([]byte(titles[i-1]), []byte(titles[i])) > 0 {
+			t.Errorf("front articles `%s` and `%s` are not in title order", titles[i-1], titles[i])
+		}
+	}
+	var entries, entriesErr = z.FrontArticlesWithTitlePrefix([]byte(titles[0][:1]), 0)
+	if entriesErr != nil || len(entries) == 0 || string(entries[0].Title()) != titles[0] {
+		t.Errorf("z.FrontArticlesWithTitlePrefix() returned %v, %v; want %s first", entries, entriesErr, titles[0])
+	}
+}
+
+// failingReaderAt returns an error for all reads while fail is set.
+type failingReaderAt struct {
+	r    io.ReaderAt
+	fail bool
+}
+
+func (f *failingReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if f.fail {
+		return 0, errors.New("read failed")
+	}
+	return f.r.ReadAt(p, offset)
+}
+
+func TestFrontArticlesRetry(t *testing.T) {
+	var data = writeTestFile(t, WriterOptions{}, writerTestEntries)
+	var reader = &failingReaderAt{r: bytes.NewReader(data)}
+	var zf, openErr = OpenReaderAt(reader, int64(len(data)))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	// a failed read is retried
+	reader.fail = true
+	if _, err := zf.FrontArticleCount(); err == nil {
+		t.Fatal("zf.FrontArticleCount() returned no error while reading fails")
+	}
+	reader.fail = false
+	if count, err := zf.FrontArticleCount(); err != nil || count != 3 {
+		t.Errorf("zf.FrontArticleCount() returned %d, `%v`; want 3", count, err)
+	}
+	// a successful read is kept
+	reader.fail = true
+	if count, err := zf.FrontArticleCount(); err != nil || count != 3 {
+		t.Errorf("zf.FrontArticleCount() returned %d, `%v` after reading succeeded once", count, err)
+	}
+}
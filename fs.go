@@ -0,0 +1,398 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS is a read-only file system for the contents of a ZIM file.
+// It implements fs.FS, fs.ReadDirFS, fs.StatFS and fs.ReadFileFS.
+//
+// Every namespace is a top-level directory, so the path of a Directory Entry
+// is its namespace followed by its URL, e.g. "A/Foo.html". Slashes in URLs
+// form sub directories. Redirects are followed, so opening a Redirect Entry
+// returns the contents of its target. Directory Entries whose path is no valid
+// fs path (see fs.ValidPath), Deleted Entries and LinkTargets are not part of FS.
+type FS struct {
+	z *File
+}
+
+// FS returns a file system for the contents of the ZIM file.
+func (z *File) FS() *FS {
+	return &FS{z: z}
+}
+
+// EntryInfo is returned by the Sys method of the fs.FileInfo of a file in FS.
+type EntryInfo struct {
+	Entry    DirectoryEntry // the Directory Entry storing the contents; the target of a Redirect
+	Mimetype string         // the Mimetype of the contents as found in the Mimetype list
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// splitPath returns the namespace and URL of a path.
+// ok is false if the path can't belong to a Directory Entry or a directory.
+func splitPath(name string) (namespace Namespace, url string, ok bool) {
+	var nsName, rest, _ = strings.Cut(name, "/")
+	if len(nsName) != 1 {
+		return 0, "", false
+	}
+	return Namespace(nsName[0]), rest, true
+}
+
+// Open opens the named file or directory.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var info, err = fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		return &fsDir{fsys: fsys, info: info, path: name}, nil
+	}
+	var blob, blobErr = fsys.z.BlobSeeker(&info.entryInfo.Entry)
+	if blobErr != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: blobErr}
+	}
+	return &fsFile{info: info, blob: blob}, nil
+}
+
+// Stat returns a fs.FileInfo describing the named file or directory.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	var info, err = fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	var f, err = fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, isDir := f.(*fsDir); isDir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDirectory}
+	}
+	var data, readErr = io.ReadAll(f)
+	if readErr != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: readErr}
+	}
+	return data, nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by filename.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var info, err = fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDirectory}
+	}
+	var entries, readDirErr = fsys.readDir(name)
+	if readDirErr != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: readDirErr}
+	}
+	return entries, nil
+}
+
+var (
+	errIsDirectory  = errors.New("is a directory")
+	errNotDirectory = errors.New("not a directory")
+)
+
+func (fsys *FS) stat(name string) (*fsFileInfo, error) {
+	if name == "." {
+		return &fsFileInfo{name: ".", dir: true}, nil
+	}
+	var namespace, url, ok = splitPath(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	var z = fsys.z
+	if len(url) > 0 {
+		var entry, _, err = z.EntryWithURL(namespace, []byte(url))
+		if err == nil {
+			return fsys.fileInfo(path.Base(name), entry)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		url += "/"
+	}
+	// a directory exists, if there is a Directory Entry below it
+	var _, _, err = z.EntryWithURLPrefix(namespace, []byte(url))
+	if errors.Is(err, ErrNotFound) {
+		return nil, fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fsFileInfo{name: path.Base(name), dir: true}, nil
+}
+
+func (fsys *FS) fileInfo(name string, entry DirectoryEntry) (*fsFileInfo, error) {
+	var z = fsys.z
+	if entry.IsRedirect() {
+		var err error
+		if entry, err = z.FollowRedirect(&entry); err != nil {
+			return nil, err
+		}
+		if entry.IsRedirect() {
+			return nil, fs.ErrNotExist
+		}
+	}
+	if entry.IsDeletedEntry() || entry.IsLinkTarget() {
+		return nil, fs.ErrNotExist
+	}
+	var size, err = z.blobSize(entry.ClusterNumber(), entry.BlobNumber())
+	if err != nil {
+		return nil, err
+	}
+	var info = &fsFileInfo{name: name, size: size, entryInfo: &EntryInfo{Entry: entry}}
+	info.entryInfo.Mimetype, _ = z.MimetypeOf(&entry)
+	return info, nil
+}
+
+// readDir collects the files and sub directories of a directory.
+// The Directory Entries below a sub directory are skipped by a binary search.
+func (fsys *FS) readDir(name string) ([]fs.DirEntry, error) {
+	var z = fsys.z
+	var entries []fs.DirEntry
+	var entryIndex = make(map[string]int)
+	var addEntry = func(childName string, isDir bool) {
+		var childPath = path.Join(name, childName)
+		if childName == "" || childName == "." || !fs.ValidPath(childPath) || strings.Contains(childName, "/") {
+			return
+		}
+		// a file takes precedence over a directory with the same name
+		var childEntry = &fsDirEntry{fsys: fsys, path: childPath, dir: isDir}
+		if i, exists := entryIndex[childName]; exists {
+			if !isDir {
+				entries[i] = childEntry
+			}
+			return
+		}
+		entryIndex[childName] = len(entries)
+		entries = append(entries, childEntry)
+	}
+
+	if name == "." {
+		// every namespace is a directory
+		var position uint32
+		for position < z.ArticleCount() {
+			var entry, err = z.EntryAtURLPosition(position)
+			if err != nil {
+				return nil, err
+			}
+			addEntry(entry.Namespace().String(), true)
+			if entry.Namespace() == Namespace(0xFF) {
+				break
+			}
+			if position, err = z.urlLowerBound(entry.Namespace()+1, nil); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	var namespace, prefix, _ = splitPath(name)
+	if len(prefix) > 0 {
+		prefix += "/"
+	}
+	var position, err = z.urlLowerBound(namespace, []byte(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for position < z.ArticleCount() {
+		var entry DirectoryEntry
+		if entry, err = z.EntryAtURLPosition(position); err != nil {
+			return nil, err
+		}
+		if entry.Namespace() != namespace || !bytes.HasPrefix(entry.URL(), []byte(prefix)) {
+			break
+		}
+		var rest = string(entry.URL()[len(prefix):])
+		if dirName, _, isDir := strings.Cut(rest, "/"); isDir {
+			addEntry(dirName, true)
+			// '0' follows '/', so this is the first position after the sub directory
+			if position, err = z.urlLowerBound(namespace, []byte(prefix+dirName+"0")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !entry.IsDeletedEntry() && !entry.IsLinkTarget() {
+			addEntry(rest, false)
+		}
+		position++
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// fsFileInfo implements fs.FileInfo for the files and directories of FS.
+type fsFileInfo struct {
+	name      string
+	size      int64
+	dir       bool
+	entryInfo *EntryInfo // nil for directories
+}
+
+func (i *fsFileInfo) Name() string       { return i.name }
+func (i *fsFileInfo) Size() int64        { return i.size }
+func (i *fsFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *fsFileInfo) IsDir() bool        { return i.dir }
+
+func (i *fsFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// Sys returns an *EntryInfo for files and nil for directories.
+func (i *fsFileInfo) Sys() interface{} {
+	if i.entryInfo == nil {
+		return nil
+	}
+	return i.entryInfo
+}
+
+// fsDirEntry implements fs.DirEntry, the file info is read on demand.
+type fsDirEntry struct {
+	fsys *FS
+	path string
+	dir  bool
+}
+
+func (d *fsDirEntry) Name() string { return path.Base(d.path) }
+func (d *fsDirEntry) IsDir() bool  { return d.dir }
+
+func (d *fsDirEntry) Type() fs.FileMode {
+	if d.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (d *fsDirEntry) Info() (fs.FileInfo, error) {
+	return d.fsys.Stat(d.path)
+}
+
+// fsFile is an opened file of FS. It reads the blob with a BlobSeeker,
+// so it can seek and read at any offset.
+type fsFile struct {
+	info   *fsFileInfo
+	blob   BlobSeeker
+	closed bool
+}
+
+var _ io.ReaderAt = (*fsFile)(nil)
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *fsFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	return f.blob.Read(p)
+}
+
+func (f *fsFile) ReadAt(p []byte, offset int64) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	return f.blob.ReadAt(p, offset)
+}
+
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	var position, err = f.blob.Seek(offset, whence)
+	if err != nil {
+		return position, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	return position, nil
+}
+
+func (f *fsFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// fsDir is an opened directory of FS.
+type fsDir struct {
+	fsys    *FS
+	info    *fsFileInfo
+	path    string
+	entries []fs.DirEntry
+	read    bool // entries were read
+	offset  int
+	closed  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errIsDirectory}
+}
+
+func (d *fsDir) Close() error {
+	if d.closed {
+		return &fs.PathError{Op: "close", Path: d.path, Err: fs.ErrClosed}
+	}
+	d.closed = true
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: d.path, Err: fs.ErrClosed}
+	}
+	if !d.read {
+		var entries, err = d.fsys.readDir(d.path)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.path, Err: err}
+		}
+		d.entries, d.read = entries, true
+	}
+	var remaining = d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n:n], nil
+}
@@ -0,0 +1,182 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSConformance(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+	var fsys = zm.FS()
+	if err := fstest.TestFS(fsys, "A/index.html", "A/zstd.html", "A/notes.txt", "A/Redirect.html", "-/style.css", "M/Title"); err != nil {
+		t.Error(err)
+	}
+
+	var redirect, redirectErr = fsys.ReadFile("A/Redirect.html")
+	if redirectErr != nil {
+		t.Fatal(redirectErr)
+	}
+	var target, targetErr = fsys.ReadFile("A/zstd.html")
+	if targetErr != nil {
+		t.Fatal(targetErr)
+	}
+	if !bytes.Equal(redirect, target) {
+		t.Error("fsys.ReadFile() of a Redirect Entry doesn't return the contents of the target")
+	}
+}
+
+func TestFSEntries(t *testing.T) {
+	var fsys = z.FS()
+
+	var rootEntries, rootErr = fsys.ReadDir(".")
+	if rootErr != nil {
+		t.Fatal(rootErr)
+	}
+	var namespaces []string
+	for _, entry := range rootEntries {
+		if !entry.IsDir() {
+			t.Errorf("root entry %s is no directory", entry.Name())
+		}
+		namespaces = append(namespaces, entry.Name())
+	}
+	var expectedNamespaces = []string{"-", "A", "I", "M", "Z"}
+	if fmt.Sprint(namespaces) != fmt.Sprint(expectedNamespaces) {
+		t.Errorf("fsys.ReadDir(\".\") returned %v; want %v", namespaces, expectedNamespaces)
+	}
+
+	var info, statErr = fsys.Stat("A/index.htm")
+	if statErr != nil {
+		t.Fatal(statErr)
+	}
+	const expectedSize = 1982
+	if info.Size() != expectedSize || info.IsDir() || info.Name() != "index.htm" {
+		t.Errorf("fsys.Stat() returned name %s, size %d, directory %t; want index.htm, %d, false",
+			info.Name(), info.Size(), info.IsDir(), expectedSize)
+	}
+	var entryInfo, isEntryInfo = info.Sys().(*EntryInfo)
+	if !isEntryInfo || entryInfo.Mimetype != "text/html" || string(entryInfo.Entry.URL()) != "index.htm" {
+		t.Errorf("info.Sys() returned %v; want *EntryInfo with Mimetype text/html", info.Sys())
+	}
+
+	var redirectInfo, redirectErr = fsys.Stat("A/Orbite_heliosynchrone.html")
+	if redirectErr != nil {
+		t.Fatal(redirectErr)
+	}
+	if target := redirectInfo.Sys().(*EntryInfo).Entry.URL(); string(target) != "Orbite_héliosynchrone.html" {
+		t.Errorf("Redirect Entry was resolved to `%s`; want `%s`", target, "Orbite_héliosynchrone.html")
+	}
+
+	var files int
+	var walkErr = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if walkErr != nil {
+		t.Error(walkErr)
+	}
+	if files == 0 || files > int(z.ArticleCount()) {
+		t.Errorf("fs.WalkDir() found %d files; want between 1 and %d", files, z.ArticleCount())
+	}
+
+	for _, name := range []string{"A/doesnotexist.html", "Q", "AB/index.htm", "A/index.htm/x"} {
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("fsys.Open(%q) returned `%v`; want fs.ErrNotExist", name, err)
+		}
+	}
+	if _, err := fsys.Open("/A/index.htm"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("fsys.Open() of an invalid path returned `%v`; want fs.ErrInvalid", err)
+	}
+}
+
+func TestFSSeek(t *testing.T) {
+	var f, openErr = z.FS().Open("A/index.htm")
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer f.Close()
+	var seeker = f.(io.ReadSeeker)
+	var all, _ = ioutil.ReadAll(seeker)
+	for _, offset := range []int64{100, 10, 1500, 0} {
+		if position, err := seeker.Seek(offset, io.SeekStart); err != nil || position != offset {
+			t.Fatalf("Seek(%d) returned %d, %v", offset, position, err)
+		}
+		var buf = make([]byte, 50)
+		if _, err := io.ReadFull(seeker, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, all[offset:offset+50]) {
+			t.Errorf("read after Seek(%d) returned wrong data", offset)
+		}
+	}
+	var buf = make([]byte, 50)
+	if n, err := f.(io.ReaderAt).ReadAt(buf, 20); err != nil || !bytes.Equal(buf[:n], all[20:70]) {
+		t.Errorf("ReadAt(20) returned %d bytes, `%v`", n, err)
+	}
+	if _, err := seeker.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Seek(-1) returned `%v`; want fs.ErrInvalid", err)
+	}
+}
+
+func TestFSFileSize(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+	// the size is read from the blob offsets of uncompressed and compressed clusters
+	for entry, err := range zm.Entries(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.IsRedirect() || entry.IsLinkTarget() || entry.IsDeletedEntry() {
+			continue
+		}
+		var _, blobSize, readerErr = zm.BlobReader(&entry)
+		if readerErr != nil {
+			t.Fatal(readerErr)
+		}
+		var info, statErr = zm.FS().Stat(entry.Namespace().String() + "/" + string(entry.URL()))
+		if statErr != nil {
+			continue // no valid fs path
+		}
+		if info.Size() != blobSize {
+			t.Errorf("size of %s was %d; want %d", entry.String(), info.Size(), blobSize)
+		}
+	}
+	if _, err := zm.blobSize(0, 1000); !errors.Is(err, ErrInvalidBlob) {
+		t.Errorf("zm.blobSize(0, 1000) returned `%v`; want `%s`", err, ErrInvalidBlob)
+	}
+}
+
+func TestFSHTTP(t *testing.T) {
+	var server = httptest.NewServer(http.FileServer(http.FS(z.FS())))
+	defer server.Close()
+	var response, getErr = http.Get(server.URL + "/A/index.htm")
+	if getErr != nil {
+		t.Fatal(getErr)
+	}
+	defer response.Body.Close()
+	var body, _ = ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || len(body) != 1982 {
+		t.Errorf("GET /A/index.htm returned status %d with %d bytes; want %d with %d bytes",
+			response.StatusCode, len(body), http.StatusOK, 1982)
+	}
+}
@@ -1,8 +1,10 @@
 package zim
 
 import (
+	"crypto/md5"
+	"encoding/binary"
 	"encoding/hex"
-	"errors"
+	"fmt"
 )
 
 const uuidLen = 16
@@ -31,33 +33,62 @@ type Header struct {
 	checksumPos   uint64 // pointer to the MD5 checksum of the ZIM file without the checksum itself. This points always 16 bytes before the end of the file.
 }
 
+const headerLen = 80
+
 func (z *File) readHeader() error {
-	seek(z.f, 0)
-	z.header.magicNumber = readUint32(z.f)
+	var buf, readErr = readSliceAt(z.r, 0, headerLen)
+	if readErr != nil {
+		return readError("read header", 0, readErr, ErrInvalidHeader)
+	}
+	z.header.magicNumber = binary.LittleEndian.Uint32(buf[0:4])
 	if z.header.magicNumber != MagicNumber {
-		return errors.New("zim: file has no ZIM header")
-	}
-	z.header.majorVersion = readUint16(z.f)
-	z.header.minorVersion = readUint16(z.f)
-	var uuidReadErr error
-	z.header.uuid, uuidReadErr = readSlice(z.f, uuidLen)
-	if uuidReadErr != nil {
-		return uuidReadErr
-	}
-	z.header.articleCount = readUint32(z.f)
-	z.header.clusterCount = readUint32(z.f)
-	z.header.urlPtrPos = readUint64(z.f)
-	z.header.titlePtrPos = readUint64(z.f)
-	z.header.clusterPtrPos = readUint64(z.f)
-	z.header.mimeListPos = readUint64(z.f)
-	z.header.mainPage = readUint32(z.f)
-	z.header.layoutPage = readUint32(z.f)
-	z.header.checksumPos = readUint64(z.f)
+		return &ReadError{Op: "read header", Offset: 0, Err: ErrInvalidHeader}
+	}
+	z.header.majorVersion = binary.LittleEndian.Uint16(buf[4:6])
+	z.header.minorVersion = binary.LittleEndian.Uint16(buf[6:8])
+	z.header.uuid = UUID(buf[8 : 8+uuidLen])
+	z.header.articleCount = binary.LittleEndian.Uint32(buf[24:28])
+	z.header.clusterCount = binary.LittleEndian.Uint32(buf[28:32])
+	z.header.urlPtrPos = binary.LittleEndian.Uint64(buf[32:40])
+	z.header.titlePtrPos = binary.LittleEndian.Uint64(buf[40:48])
+	z.header.clusterPtrPos = binary.LittleEndian.Uint64(buf[48:56])
+	z.header.mimeListPos = binary.LittleEndian.Uint64(buf[56:64])
+	z.header.mainPage = binary.LittleEndian.Uint32(buf[64:68])
+	z.header.layoutPage = binary.LittleEndian.Uint32(buf[68:72])
+	z.header.checksumPos = binary.LittleEndian.Uint64(buf[72:80])
 
 	switch z.header.majorVersion {
 	case 5, 6:
-		return nil
 	default:
-		return errors.New("zim: version currently not supported")
+		return &ReadError{Op: "read header", Offset: 4, Err: ErrUnsupportedVersion}
+	}
+	return z.validateHeader()
+}
+
+// validateHeader checks that all lists of the header are inside of the file.
+func (z *File) validateHeader() error {
+	var invalid = func(field string) error {
+		return &ReadError{Op: "read header", Offset: 0, Err: fmt.Errorf("%w: %s out of range", ErrInvalidHeader, field)}
+	}
+	var checksumPos = z.header.checksumPos
+	if checksumPos > uint64(z.size) || uint64(z.size)-checksumPos < md5.Size {
+		return invalid("checksum position")
+	}
+	if z.header.mimeListPos < headerLen || z.header.mimeListPos >= checksumPos {
+		return invalid("mimetype list position")
+	}
+	var fitsBeforeChecksum = func(listPos uint64, count uint32, pointerSize uint64) bool {
+		return listPos >= headerLen && listPos <= checksumPos &&
+			uint64(count)*pointerSize <= checksumPos-listPos
+	}
+	if !fitsBeforeChecksum(z.header.urlPtrPos, z.header.articleCount, 8) {
+		return invalid("URL pointer list position")
+	}
+	if !fitsBeforeChecksum(z.header.titlePtrPos, z.header.articleCount, 4) {
+		return invalid("title pointer list position")
+	}
+	if !fitsBeforeChecksum(z.header.clusterPtrPos, z.header.clusterCount, 8) {
+		return invalid("cluster pointer list position")
 	}
+	return nil
 }
@@ -0,0 +1,286 @@
+package zim
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	httpBlockSize    = 1 << 16 // 64KB
+	httpCacheSize    = 1 << 25 // 32MB
+	httpMaxPrefetch  = httpCacheSize / 2
+	httpCachedBlocks = httpCacheSize / httpBlockSize
+	httpSizeUnknown  = -1
+	httpTimeout      = time.Minute
+)
+
+// httpDefaultClient is used by NewHTTPReaderAt without a client. Unlike http.DefaultClient,
+// it doesn't wait forever for a server that stopped responding.
+var httpDefaultClient = &http.Client{Timeout: httpTimeout}
+
+// HTTPReaderAt reads a remote file with HTTP range requests.
+// The file is read in blocks of 64KB, which are kept in a LRU cache of 32MB.
+// Adjacent blocks are requested together and concurrent reads
+// of the same block share a single request.
+// It is safe for concurrent use by multiple goroutines.
+type HTTPReaderAt struct {
+	client   *http.Client
+	url      string
+	size     int64
+	requests int64 // accessed atomically
+
+	mu       sync.Mutex
+	blocks   map[int64]*list.Element // values of the list are *httpBlock
+	lru      *list.List              // front is the most recently used block
+	inflight map[int64]*httpFetch
+}
+
+type httpBlock struct {
+	index int64
+	data  []byte
+}
+
+// httpFetch is a range request for one or more blocks, which others can wait for.
+type httpFetch struct {
+	done   chan struct{}
+	first  int64 // index of the first block
+	blocks [][]byte
+	err    error
+}
+
+// NewHTTPReaderAt returns a reader for the file at url. The first block of the file
+// is requested to get the file size. If client is nil, a client with a timeout of one minute
+// for each request is used.
+func NewHTTPReaderAt(client *http.Client, url string) (*HTTPReaderAt, error) {
+	if client == nil {
+		client = httpDefaultClient
+	}
+	var r = &HTTPReaderAt{
+		client:   client,
+		url:      url,
+		size:     httpSizeUnknown,
+		blocks:   make(map[int64]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[int64]*httpFetch),
+	}
+	var data, size, err = r.request(0, httpBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	r.size = size
+	r.mu.Lock()
+	r.cache(0, splitBlocks(data))
+	r.mu.Unlock()
+	return r, nil
+}
+
+// OpenURL opens the ZIM file at url, which is read with HTTP range requests
+// with a timeout of one minute; use NewHTTPReaderAt and OpenReaderAt for another client.
+func OpenURL(url string) (*File, error) {
+	var r, err = NewHTTPReaderAt(nil, url)
+	if err != nil {
+		return nil, err
+	}
+	return OpenReaderAt(r, r.Size())
+}
+
+// Size returns the size of the remote file.
+func (r *HTTPReaderAt) Size() int64 { return r.size }
+
+// Requests returns the number of HTTP requests sent so far.
+func (r *HTTPReaderAt) Requests() int64 { return atomic.LoadInt64(&r.requests) }
+
+// ReadAt implements io.ReaderAt.
+func (r *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("zim: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	var end = off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+	var first = off / httpBlockSize
+	var blocks, err = r.load(first, (end-1)/httpBlockSize)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for i, block := range blocks {
+		var blockStart = (first + int64(i)) * httpBlockSize
+		var from = off + int64(n) - blockStart
+		if from >= int64(len(block)) {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += copy(p[n:end-off], block[from:])
+	}
+	switch {
+	case off+int64(n) < end:
+		return n, io.ErrUnexpectedEOF
+	case n < len(p):
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// prefetch loads a range of the file with as few requests as possible.
+// It implements the prefetcher interface.
+func (r *HTTPReaderAt) prefetch(off, n int64) error {
+	if n > httpMaxPrefetch {
+		n = httpMaxPrefetch
+	}
+	if off < 0 || n <= 0 || off >= r.size {
+		return nil
+	}
+	var end = off + n
+	if end > r.size {
+		end = r.size
+	}
+	var _, err = r.load(off/httpBlockSize, (end-1)/httpBlockSize)
+	return err
+}
+
+// load returns the blocks first to last. Missing blocks are requested
+// in runs of adjacent blocks, blocks requested by others are waited for.
+func (r *HTTPReaderAt) load(first, last int64) ([][]byte, error) {
+	var blocks = make([][]byte, last-first+1)
+	var waitFor = make(map[int64]*httpFetch)
+	var own []*httpFetch
+
+	r.mu.Lock()
+	var run *httpFetch
+	for index := first; index <= last; index++ {
+		if element, cached := r.blocks[index]; cached {
+			r.lru.MoveToFront(element)
+			blocks[index-first] = element.Value.(*httpBlock).data
+			run = nil
+			continue
+		}
+		if fetch, requested := r.inflight[index]; requested {
+			waitFor[index] = fetch
+			run = nil
+			continue
+		}
+		if run == nil {
+			run = &httpFetch{done: make(chan struct{}), first: index}
+			own = append(own, run)
+		}
+		run.blocks = append(run.blocks, nil)
+		r.inflight[index] = run
+		waitFor[index] = run
+	}
+	r.mu.Unlock()
+
+	for _, fetch := range own {
+		var count = int64(len(fetch.blocks))
+		var data, _, err = r.request(fetch.first*httpBlockSize, count*httpBlockSize)
+		if err == nil && !r.complete(fetch.first, data) {
+			err = io.ErrUnexpectedEOF
+		}
+		r.mu.Lock()
+		if err == nil {
+			fetch.blocks = splitBlocks(data)
+			r.cache(fetch.first, fetch.blocks)
+		}
+		fetch.err = err
+		for index := fetch.first; index < fetch.first+count; index++ {
+			delete(r.inflight, index)
+		}
+		r.mu.Unlock()
+		close(fetch.done)
+	}
+
+	for index, fetch := range waitFor {
+		<-fetch.done
+		if fetch.err != nil {
+			return nil, fetch.err
+		}
+		var i = index - fetch.first
+		if i >= int64(len(fetch.blocks)) || fetch.blocks[i] == nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		blocks[index-first] = fetch.blocks[i]
+	}
+	return blocks, nil
+}
+
+// complete reports whether data, which starts at the block index first, consists of full blocks
+// and a final block ending at the end of the file, so that it can be cached.
+func (r *HTTPReaderAt) complete(first int64, data []byte) bool {
+	var end = first*httpBlockSize + int64(len(data))
+	return len(data)%httpBlockSize == 0 || end == r.size
+}
+
+// cache stores blocks starting at the block index first and removes
+// the least recently used blocks. r.mu must be held.
+func (r *HTTPReaderAt) cache(first int64, blocks [][]byte) {
+	for i, data := range blocks {
+		var index = first + int64(i)
+		if element, cached := r.blocks[index]; cached {
+			r.lru.MoveToFront(element)
+			continue
+		}
+		r.blocks[index] = r.lru.PushFront(&httpBlock{index: index, data: data})
+	}
+	for r.lru.Len() > httpCachedBlocks {
+		var block = r.lru.Remove(r.lru.Back()).(*httpBlock)
+		delete(r.blocks, block.index)
+	}
+}
+
+// splitBlocks splits data, which starts at a block boundary, into blocks.
+func splitBlocks(data []byte) [][]byte {
+	var blocks [][]byte
+	for len(data) > 0 {
+		var n = httpBlockSize
+		if n > len(data) {
+			n = len(data)
+		}
+		blocks = append(blocks, data[:n:n])
+		data = data[n:]
+	}
+	return blocks
+}
+
+// request sends a range request and returns the data and the size of the file.
+func (r *HTTPReaderAt) request(off, n int64) (data []byte, size int64, err error) {
+	if r.size != httpSizeUnknown && off+n > r.size {
+		n = r.size - off
+	}
+	var req, reqErr = http.NewRequest(http.MethodGet, r.url, nil)
+	if reqErr != nil {
+		return nil, 0, reqErr
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+	atomic.AddInt64(&r.requests, 1)
+	var resp, respErr = r.client.Do(req)
+	if respErr != nil {
+		return nil, 0, respErr
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		if resp.StatusCode == http.StatusOK {
+			return nil, 0, ErrNoRangeSupport
+		}
+		return nil, 0, fmt.Errorf("zim: range request for %s failed: %s", r.url, resp.Status)
+	}
+	var start, end int64
+	// the server may send less than requested only at the end of the file
+	if _, scanErr := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size); scanErr != nil ||
+		start != off || end < start || end-start >= n || end >= size || end-start+1 < n && end+1 != size {
+		return nil, 0, fmt.Errorf("zim: invalid Content-Range %q for %s", resp.Header.Get("Content-Range"), r.url)
+	}
+	data = make([]byte, end-start+1)
+	if _, err = io.ReadFull(resp.Body, data); err != nil {
+		return nil, 0, err
+	}
+	return data, size, nil
+}
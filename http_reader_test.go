@@ -0,0 +1,188 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testdataServer serves the testdata directory and counts the requests.
+func testdataServer(t *testing.T, delay time.Duration) (*httptest.Server, *int64) {
+	var requests int64
+	var files = http.FileServer(http.Dir("testdata"))
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		time.Sleep(delay)
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOpenURL(t *testing.T) {
+	var server, requests = testdataServer(t, 0)
+	var zr, openErr = OpenURL(server.URL + "/" + filenameTestfile)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zr.Close()
+	if zr.ArticleCount() != z.ArticleCount() || zr.UUID().String() != z.UUID().String() {
+		t.Errorf("OpenURL() read a different header than Open()")
+	}
+
+	var opened = atomic.LoadInt64(requests)
+	var entry, _, entryErr = zr.EntryWithURL(NamespaceArticles, []byte("index.htm"))
+	if entryErr != nil {
+		t.Fatal(entryErr)
+	}
+	var reader, _, readerErr = zr.BlobReader(&entry)
+	if readerErr != nil {
+		t.Fatal(readerErr)
+	}
+	var remote, _ = ioutil.ReadAll(reader)
+	const maxRequests = 5
+	if n := atomic.LoadInt64(requests) - opened; n > maxRequests {
+		t.Errorf("EntryWithURL() and BlobReader() needed %d requests; want at most %d", n, maxRequests)
+	}
+
+	var localEntry, _, _ = z.EntryWithURL(NamespaceArticles, []byte("index.htm"))
+	var localReader, _, _ = z.BlobReader(&localEntry)
+	var local, _ = ioutil.ReadAll(localReader)
+	if len(remote) == 0 || !bytes.Equal(remote, local) {
+		t.Errorf("blob read with OpenURL() has %d bytes and differs from the local blob with %d bytes", len(remote), len(local))
+	}
+
+	if err := zr.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPReaderAtCoalescing(t *testing.T) {
+	var server, requests = testdataServer(t, 50*time.Millisecond)
+	var r, err = NewHTTPReaderAt(server.Client(), server.URL+"/"+filenameTestfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != 792439 {
+		t.Errorf("r.Size() returned %d; want %d", r.Size(), 792439)
+	}
+	var local, _ = ioutil.ReadFile(path.Join("testdata", filenameTestfile))
+
+	// concurrent reads of the same blocks share a single request
+	const offset = 3*httpBlockSize - 100
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf = make([]byte, httpBlockSize)
+			if _, err := r.ReadAt(buf, offset); err != nil {
+				t.Error(err)
+			} else if !bytes.Equal(buf, local[offset:offset+httpBlockSize]) {
+				t.Error("r.ReadAt() returned wrong data")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := r.Requests(); n != 2 || atomic.LoadInt64(requests) != 2 {
+		t.Errorf("r.Requests() returned %d; want 2", n)
+	}
+
+	// adjacent missing blocks are requested together
+	var buf = make([]byte, 5*httpBlockSize)
+	if _, err := r.ReadAt(buf, 5*httpBlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Requests(); n != 3 {
+		t.Errorf("r.Requests() returned %d; want 3", n)
+	}
+
+	// reads after the end are short
+	var tail = make([]byte, 100)
+	if n, err := r.ReadAt(tail, r.Size()-10); n != 10 || err == nil {
+		t.Errorf("r.ReadAt() at the end returned %d, %v; want 10, io.EOF", n, err)
+	}
+}
+
+func TestOpenURLNoRangeSupport(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ZIM"))
+	}))
+	defer server.Close()
+	if _, err := OpenURL(server.URL); !errors.Is(err, ErrNoRangeSupport) {
+		t.Errorf("OpenURL() returned `%v`; want `%s`", err, ErrNoRangeSupport)
+	}
+}
+
+func TestHTTPReaderAtInvalidRange(t *testing.T) {
+	for _, contentRange := range []string{
+		"bytes 0-1073741823/1073741824", // more than requested
+		"bytes 1-65536/1073741824",      // another start
+		"bytes 0-65535/100",             // after the end of the file
+		"bytes 0-65535",
+	} {
+		var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", contentRange)
+			w.WriteHeader(http.StatusPartialContent)
+		}))
+		if _, err := NewHTTPReaderAt(server.Client(), server.URL); err == nil {
+			t.Errorf("NewHTTPReaderAt() with Content-Range %q returned no error", contentRange)
+		}
+		server.Close()
+	}
+	if httpDefaultClient.Timeout == 0 {
+		t.Error("the default client has no timeout")
+	}
+}
+
+func TestHTTPReaderAtTruncatedRange(t *testing.T) {
+	var data = make([]byte, 200000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	var truncate atomic.Bool
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end int64
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		if end >= int64(len(data)) {
+			end = int64(len(data)) - 1
+		}
+		if truncate.Load() && start > 0 {
+			// a valid Content-Range, but shorter than requested and not at the end of the file
+			end = start + (end-start)/2
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+	defer server.Close()
+
+	var r, err = NewHTTPReaderAt(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf = make([]byte, 1000)
+	truncate.Store(true)
+	if _, err = r.ReadAt(buf, 70000); err == nil {
+		t.Error("r.ReadAt() of a truncated range returned no error")
+	}
+	// nothing of the truncated range was cached
+	truncate.Store(false)
+	for _, off := range []int64{70000, 131000, 100000} {
+		if n, err := r.ReadAt(buf, off); err != nil || !bytes.Equal(buf[:n], data[off:off+1000]) {
+			t.Errorf("r.ReadAt(%d) returned %d bytes, `%v`", off, n, err)
+		}
+	}
+	if n, err := r.ReadAt(buf, int64(len(data))-50); n != 50 || err != io.EOF {
+		t.Errorf("r.ReadAt() at the end returned %d, `%v`; want 50, io.EOF", n, err)
+	}
+}
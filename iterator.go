@@ -0,0 +1,173 @@
+package zim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"iter"
+)
+
+const (
+	// iteratorPointers is the number of pointers read at once by the iterators.
+	iteratorPointers = 1024
+	// streamBufferSize is the buffer size for Directory Entries read in sequence.
+	streamBufferSize = 1 << 16
+)
+
+// direntStream reads Directory Entries with a large buffer. Writers mostly store the
+// Directory Entries in URL order, so the next Directory Entry is often already buffered.
+type direntStream struct {
+	z        *File
+	r        dataReader
+	buffered *bufio.Reader
+	position int64 // file position of the next byte of r
+}
+
+func (s *direntStream) entryAt(pointer uint64) (DirectoryEntry, error) {
+	if s.r == nil || int64(pointer) != s.position {
+		var position = int64(pointer)
+		if s.z.isMapped() {
+			s.r = s.z.dataReaderAt(position)
+		} else {
+			var section = io.NewSectionReader(s.z.r, position, s.z.size-position)
+			if s.buffered == nil {
+				s.buffered = bufio.NewReaderSize(section, streamBufferSize)
+			} else {
+				s.buffered.Reset(section)
+			}
+			s.r = s.buffered
+		}
+		s.position = position
+	}
+	var entry, err = s.z.decodeDirectoryEntry(s.r, pointer)
+	if err != nil {
+		s.r = nil
+		return entry, err
+	}
+	s.position += entry.encodedLen()
+	return entry, nil
+}
+
+// isMapped reports whether the file was opened by OpenMmap.
+func (z *File) isMapped() bool {
+	var _, mapped = z.r.(mappedFile)
+	return mapped
+}
+
+// urlPointersAt reads count pointers of the URL pointerlist beginning at the position.
+func (z *File) urlPointersAt(position, count uint32) ([]uint64, error) {
+	const op = "read URL pointer"
+	var offset = int64(z.header.urlPtrPos) + int64(position)*8
+	var data, err = readSliceAt(z.r, offset, int(count)*8)
+	if err != nil {
+		return nil, readError(op, offset, err, ErrInvalidPointer)
+	}
+	var pointers = make([]uint64, count)
+	for i := range pointers {
+		pointers[i] = binary.LittleEndian.Uint64(data[i*8:])
+		if pointers[i] >= z.header.checksumPos {
+			return nil, &ReadError{Op: op, Offset: offset + int64(i)*8, Err: ErrInvalidPointer}
+		}
+	}
+	return pointers, nil
+}
+
+// titlePointersAt reads the pointers to the Directory Entries of count
+// positions of the title pointerlist beginning at the position.
+func (z *File) titlePointersAt(position, count uint32) ([]uint64, error) {
+	const op = "read title pointer"
+	var offset = int64(z.header.titlePtrPos) + int64(position)*4
+	var data, err = readSliceAt(z.r, offset, int(count)*4)
+	if err != nil {
+		return nil, readError(op, offset, err, ErrInvalidPointer)
+	}
+	var pointers = make([]uint64, count)
+	for i := range pointers {
+		var urlPosition = binary.LittleEndian.Uint32(data[i*4:])
+		if urlPosition >= z.header.articleCount {
+			return nil, &ReadError{Op: op, Offset: offset + int64(i)*4, Err: ErrInvalidPointer}
+		}
+		if pointers[i], err = z.urlPointerAtPos(urlPosition); err != nil {
+			return nil, err
+		}
+	}
+	return pointers, nil
+}
+
+// iterate returns an iterator over the Directory Entries from the start position on,
+// whose pointers are read by pointersAt. The iteration stops when accept returns false.
+func (z *File) iterate(start uint32, pointersAt func(position, count uint32) ([]uint64, error),
+	accept func(*DirectoryEntry) bool) iter.Seq2[DirectoryEntry, error] {
+	return func(yield func(DirectoryEntry, error) bool) {
+		var stream = direntStream{z: z}
+		for position := start; position < z.header.articleCount; {
+			var count = z.header.articleCount - position
+			if count > iteratorPointers {
+				count = iteratorPointers
+			}
+			var pointers, err = pointersAt(position, count)
+			if err != nil {
+				yield(DirectoryEntry{}, err)
+				return
+			}
+			for _, pointer := range pointers {
+				var entry, entryErr = stream.entryAt(pointer)
+				if entryErr != nil {
+					yield(DirectoryEntry{}, entryErr)
+					return
+				}
+				if accept != nil && !accept(&entry) {
+					return
+				}
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			position += count
+		}
+	}
+}
+
+// Entries returns an iterator over the Directory Entries in URL order,
+// beginning at the start position of the URL pointerlist:
+//
+//	for entry, err := range z.Entries(0) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The pointerlist is read in large parts. If reading fails, the error is
+// yielded with an empty Directory Entry and the iteration stops.
+// Redirects are not followed automatically.
+func (z *File) Entries(start uint32) iter.Seq2[DirectoryEntry, error] {
+	return z.iterate(start, z.urlPointersAt, nil)
+}
+
+// EntriesByTitle returns an iterator over the Directory Entries in title order,
+// beginning at the start position of the title pointerlist.
+// If reading fails, the error is yielded with an empty Directory Entry and the iteration stops.
+// Redirects are not followed automatically.
+func (z *File) EntriesByTitle(start uint32) iter.Seq2[DirectoryEntry, error] {
+	return z.iterate(start, z.titlePointersAt, nil)
+}
+
+// EntriesInNamespace returns an iterator over the Directory Entries in the namespace
+// having the given URL prefix in URL order. A nil prefix iterates over the whole namespace.
+// If reading fails, the error is yielded with an empty Directory Entry and the iteration stops.
+// Redirects are not followed automatically.
+func (z *File) EntriesInNamespace(namespace Namespace, prefix []byte) iter.Seq2[DirectoryEntry, error] {
+	return func(yield func(DirectoryEntry, error) bool) {
+		var start, err = z.urlLowerBound(namespace, prefix)
+		if err != nil {
+			yield(DirectoryEntry{}, err)
+			return
+		}
+		var inNamespace = func(entry *DirectoryEntry) bool {
+			return entry.namespace == namespace && bytes.HasPrefix(entry.url, prefix)
+		}
+		z.iterate(start, z.urlPointersAt, inNamespace)(yield)
+	}
+}
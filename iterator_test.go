@@ -0,0 +1,160 @@
+package zim
+
+import (
+	"encoding/binary"
+	"errors"
+	"iter"
+	"path"
+	"testing"
+)
+
+func TestEntries(t *testing.T) {
+	var tests = []struct {
+		name       string
+		iterator   func(start uint32) iter.Seq2[DirectoryEntry, error]
+		entryAtPos func(position uint32) (DirectoryEntry, error)
+	}{
+		{"Entries", z.Entries, z.EntryAtURLPosition},
+		{"EntriesByTitle", z.EntriesByTitle, z.EntryAtTitlePosition},
+	}
+	for _, test := range tests {
+		for _, start := range []uint32{0, 100, z.ArticleCount() - 1, z.ArticleCount()} {
+			var position = start
+			for entry, err := range test.iterator(start) {
+				if err != nil {
+					t.Fatalf("%s(%d): %s", test.name, start, err)
+				}
+				var expected, _ = test.entryAtPos(position)
+				if entry.String() != expected.String() || entry.Mimetype() != expected.Mimetype() ||
+					entry.BlobNumber() != expected.BlobNumber() || entry.ClusterNumber() != expected.ClusterNumber() {
+					t.Errorf("%s(%d) returned %s at position %d; want %s", test.name, start, entry.String(), position, expected.String())
+				}
+				position++
+			}
+			if position != z.ArticleCount() {
+				t.Errorf("%s(%d) stopped at position %d; want %d", test.name, start, position, z.ArticleCount())
+			}
+		}
+	}
+
+	var count int
+	for range z.Entries(0) {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("iteration stopped after %d entries; want 10", count)
+	}
+}
+
+func TestEntriesInNamespace(t *testing.T) {
+	for _, test := range []struct {
+		namespace Namespace
+		prefix    string
+	}{
+		{NamespaceZimMetadata, ""},
+		{NamespaceArticles, ""},
+		{NamespaceArticles, "Orbite"},
+		{NamespaceArticles, "doesnotexist"},
+		{NamespaceImagesFiles, "m/"},
+	} {
+		var expected, expectedErr = z.EntriesWithURLPrefix(test.namespace, []byte(test.prefix), int(z.ArticleCount()))
+		if expectedErr != nil {
+			t.Fatal(expectedErr)
+		}
+		var entries []DirectoryEntry
+		for entry, err := range z.EntriesInNamespace(test.namespace, []byte(test.prefix)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) != len(expected) {
+			t.Errorf("z.EntriesInNamespace(%s, %q) returned %d entries; want %d", test.namespace, test.prefix, len(entries), len(expected))
+			continue
+		}
+		for i := range entries {
+			if entries[i].String() != expected[i].String() {
+				t.Errorf("z.EntriesInNamespace(%s, %q) returned %s; want %s", test.namespace, test.prefix, entries[i].String(), expected[i].String())
+			}
+		}
+	}
+}
+
+func TestEntriesMmap(t *testing.T) {
+	var zm, openErr = OpenMmap(path.Join("testdata", filenameTestfile))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+	var position uint32
+	for entry, err := range zm.Entries(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, _ := z.EntryAtURLPosition(position); entry.String() != expected.String() {
+			t.Errorf("zm.Entries() returned %s at position %d; want %s", entry.String(), position, expected.String())
+		}
+		position++
+	}
+	if position != z.ArticleCount() {
+		t.Errorf("zm.Entries() returned %d entries; want %d", position, z.ArticleCount())
+	}
+}
+
+func TestEntriesError(t *testing.T) {
+	// the pointer at position 2 in the URL pointerlist is after the checksum
+	var filename = corruptedTestfile(t, func(data []byte) []byte {
+		var urlPtrPos = binary.LittleEndian.Uint64(data[32:])
+		binary.LittleEndian.PutUint64(data[urlPtrPos+8*2:], uint64(len(data)))
+		return data
+	})
+	var zc, openErr = Open(filename)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zc.Close()
+	var count int
+	var lastErr error
+	for _, err := range zc.Entries(0) {
+		count++
+		lastErr = err
+	}
+	if count != 1 || !errors.Is(lastErr, ErrInvalidPointer) {
+		t.Errorf("zc.Entries() yielded %d values and `%v`; want the error `%s` only", count, lastErr, ErrInvalidPointer)
+	}
+	// the first part of the pointerlist is valid
+	count = 0
+	for _, err := range zc.Entries(3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != int(zc.ArticleCount())-3 {
+		t.Errorf("zc.Entries(3) returned %d entries; want %d", count, zc.ArticleCount()-3)
+	}
+}
+
+func BenchmarkEntries(b *testing.B) {
+	b.Run("EntryAtURLPosition", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for position := uint32(0); position < z.ArticleCount(); position++ {
+				if _, err := z.EntryAtURLPosition(position); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Entries", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range z.Entries(0) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -1,24 +1,46 @@
 package zim
 
-func (z *File) readMetadata() {
-	const entryLimit = 256 // we don't want to fill the memory too much
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxMetadataValueSize is the maximum size of the metadata values, which are read by Open.
+// Larger values, like illustrations, are read on use.
+const maxMetadataValueSize = 2048
+
+func (z *File) readMetadata() error {
 	const maxKeySize = 128
-	const maxValueSize = 2048
 	z.metadata = make(map[string]string)
-	for _, entry := range z.EntriesWithNamespace(NamespaceZimMetadata, entryLimit) {
-		if len(entry.url) <= maxKeySize {
-			var blobReader, blobSize, blobReaderErr = z.BlobReader(&entry)
-			if blobReaderErr == nil && blobSize <= maxValueSize {
-				var value = make([]byte, blobSize)
-				if _, blobReadErr := blobReader.Read(value); blobReadErr == nil {
-					z.metadata[string(entry.url)] = string(value)
-				}
+	z.metadataKeys = nil
+	for entry, err := range z.EntriesInNamespace(NamespaceZimMetadata, nil) {
+		if err != nil {
+			return err
+		}
+		if len(entry.url) > maxKeySize || entry.IsRedirect() || entry.IsLinkTarget() || entry.IsDeletedEntry() {
+			continue
+		}
+		z.metadataKeys = append(z.metadataKeys, string(entry.url))
+		var blobReader, blobSize, blobReaderErr = z.BlobReader(&entry)
+		if blobReaderErr != nil {
+			return blobReaderErr
+		}
+		if blobSize <= maxMetadataValueSize {
+			var value = make([]byte, blobSize)
+			if _, blobReadErr := io.ReadFull(blobReader, value); blobReadErr != nil {
+				return blobReadErr
 			}
+			z.metadata[string(entry.url)] = string(value)
 		}
 	}
+	return nil
 }
 
 // Metadata returns a copy of the internal metadata map of the ZIM file.
+// Values larger than 2048 bytes are not part of the map, they are read by MetadataBytes.
 func (z *File) Metadata() map[string]string {
 	var result = make(map[string]string, len(z.metadata))
 	for k, v := range z.metadata {
@@ -27,10 +49,57 @@ func (z *File) Metadata() map[string]string {
 	return result
 }
 
+// MetadataKeys returns the keys of all metadata values in sorted order,
+// including the keys of values larger than 2048 bytes.
+func (z *File) MetadataKeys() []string {
+	return append([]string(nil), z.metadataKeys...)
+}
+
+// MetadataBytes returns the metadata value for a given key, which can have up to 32MB
+// and may be binary data. Values larger than 2048 bytes are read from the file on each call.
+// If the key is not set, the returned error is ErrNotFound.
+func (z *File) MetadataBytes(key string) ([]byte, error) {
+	if value, found := z.metadata[key]; found {
+		return []byte(value), nil
+	}
+	var index = sort.SearchStrings(z.metadataKeys, key)
+	if index == len(z.metadataKeys) || z.metadataKeys[index] != key {
+		return nil, ErrNotFound
+	}
+	var entry, _, err = z.EntryWithURL(NamespaceZimMetadata, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	var blobReader, blobSize, blobReaderErr = z.BlobReader(&entry)
+	if blobReaderErr != nil {
+		return nil, blobReaderErr
+	}
+	if err = z.checkBlobSize(entry.ClusterNumber(), blobSize); err != nil {
+		return nil, err
+	}
+	var value = make([]byte, blobSize)
+	if _, err = io.ReadFull(blobReader, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 // MetadataFor returns the metadata value for a given key.
-// If the key is not set, an empty string is returned.
+// If the key is not set or reading a value larger than 2048 bytes failed, an empty string is returned.
 func (z *File) MetadataFor(key string) string {
-	return z.metadata[key]
+	if value, found := z.metadata[key]; found {
+		return value
+	}
+	var value, _ = z.MetadataBytes(key)
+	return string(value)
+}
+
+// Illustration returns the PNG image of the illustration with the size in pixels
+// (width and height) and the scale, as stored in the metadata "Illustration_{size}x{size}@{scale}".
+// Every ZIM file should have the illustration with size 48 and scale 1.
+// If the file has no such illustration, the returned error is ErrNotFound.
+func (z *File) Illustration(size, scale int) ([]byte, error) {
+	return z.MetadataBytes(fmt.Sprintf("Illustration_%dx%d@%d", size, size, scale))
 }
 
 // Name returns the Name of the ZIM file as found in the Metadata.
@@ -60,8 +129,9 @@ func (z *File) Language() string { return z.MetadataFor("Language") }
 // License returns the License of the ZIM file as found in the Metadata.
 func (z *File) License() string { return z.MetadataFor("License") }
 
-// Tags returns the Tags of the ZIM file as found in the Metadata.
-func (z *File) Tags() string { return z.MetadataFor("Tags") }
+// Tags returns the Tags of the ZIM file as found in the Metadata,
+// split into plain tags and special tags, see Tags.
+func (z *File) Tags() Tags { return parseTags(z.MetadataFor("Tags")) }
 
 // Relation returns the Relation of the ZIM file as found in the Metadata.
 func (z *File) Relation() string { return z.MetadataFor("Relation") }
@@ -69,5 +139,81 @@ func (z *File) Relation() string { return z.MetadataFor("Relation") }
 // Source returns the Source of the ZIM file as found in the Metadata.
 func (z *File) Source() string { return z.MetadataFor("Source") }
 
-// Counter returns a String containing the number of Directory Entries per Mimetype.
-func (z *File) Counter() string { return z.MetadataFor("Counter") }
+// Counter returns the number of Directory Entries per Mimetype as found in the Metadata,
+// which stores them like "text/html=1234;image/png=567".
+// Malformed pairs are skipped; a file without Counter returns an empty map.
+func (z *File) Counter() map[string]uint64 {
+	var counter = make(map[string]uint64)
+	for _, pair := range strings.Split(z.MetadataFor("Counter"), ";") {
+		var mimetype, count, found = strings.Cut(pair, "=")
+		if !found || len(mimetype) == 0 {
+			continue
+		}
+		if n, err := strconv.ParseUint(count, 10, 64); err == nil {
+			counter[mimetype] += n
+		}
+	}
+	return counter
+}
+
+// Languages returns the ISO 639-3 codes of the comma separated Language in the Metadata.
+// Empty codes are skipped.
+func (z *File) Languages() []string {
+	var languages []string
+	for _, code := range strings.Split(z.Language(), ",") {
+		if code = strings.TrimSpace(code); len(code) > 0 {
+			languages = append(languages, code)
+		}
+	}
+	return languages
+}
+
+// Tags are the semicolon separated Tags of the Metadata. Special tags start with
+// an underscore and have a value, like "_category:wikipedia" or "_pictures:no".
+// They are used by Kiwix to describe the contents of a ZIM file.
+type Tags struct {
+	Plain   []string          // tags without leading underscore in the stored order
+	Special map[string]string // values of the special tags by their key without the underscore
+}
+
+func parseTags(value string) Tags {
+	var tags = Tags{Special: make(map[string]string)}
+	for _, tag := range strings.Split(value, ";") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(tag, "_") {
+			tags.Plain = append(tags.Plain, tag)
+			continue
+		}
+		var key, tagValue, _ = strings.Cut(tag[1:], ":")
+		tags.Special[key] = tagValue
+	}
+	return tags
+}
+
+// flag returns false if the special tag has the value "no" and true for other values.
+// If the tag is not set, the default value is returned.
+func (t Tags) flag(key string, defaultValue bool) bool {
+	var value, found = t.Special[key]
+	if !found {
+		return defaultValue
+	}
+	return value != "no"
+}
+
+// Category returns the value of the special tag _category, e.g. "wikipedia".
+func (t Tags) Category() string { return t.Special["category"] }
+
+// Pictures reports whether the file contains pictures, which is true unless "_pictures:no" is set.
+func (t Tags) Pictures() bool { return t.flag("pictures", true) }
+
+// Videos reports whether the file contains videos, which is true unless "_videos:no" is set.
+func (t Tags) Videos() bool { return t.flag("videos", true) }
+
+// Details reports whether the file contains the full articles, which is true unless "_details:no" is set.
+func (t Tags) Details() bool { return t.flag("details", true) }
+
+// FTIndex reports whether the file contains a full text index, which is only true if "_ftindex" is set.
+func (t Tags) FTIndex() bool { return t.flag("ftindex", false) }
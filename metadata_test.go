@@ -0,0 +1,194 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const filenameMetadata = "metadata.zim"
+
+func TestLargeMetadata(t *testing.T) {
+	var zd, openErr = Open(path.Join("testdata", filenameMetadata))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zd.Close()
+
+	var expectedKeys = []string{"Counter", "Creator", "Date", "Description", "Illustration_48x48@1",
+		"Illustration_96x96@2", "Language", "LongDescription", "Name", "Publisher", "Tags", "Title"}
+	if keys := zd.MetadataKeys(); strings.Join(keys, ",") != strings.Join(expectedKeys, ",") {
+		t.Errorf("zd.MetadataKeys() was %v; want %v", keys, expectedKeys)
+	}
+
+	// larger values are not read by Open, but MetadataFor stays compatible
+	var longDescription = zd.LongDescription()
+	if len(longDescription) <= maxMetadataValueSize || !strings.HasPrefix(longDescription, "A long description") {
+		t.Errorf("zd.LongDescription() returned %d bytes", len(longDescription))
+	}
+	if _, found := zd.Metadata()["LongDescription"]; found {
+		t.Error("zd.Metadata() contains the LongDescription larger than 2048 bytes")
+	}
+	if value, err := zd.MetadataBytes("LongDescription"); err != nil || string(value) != longDescription {
+		t.Errorf("zd.MetadataBytes(LongDescription) returned %d bytes, `%v`", len(value), err)
+	}
+	if value, err := zd.MetadataBytes("Title"); err != nil || string(value) != "Metadata test" {
+		t.Errorf("zd.MetadataBytes(Title) returned `%s`, `%v`", value, err)
+	}
+
+	for _, test := range []struct {
+		size, scale int
+		length      int
+	}{
+		{48, 1, 608},
+		{96, 2, 5008}, // stored in a compressed cluster
+	} {
+		var illustration, err = zd.Illustration(test.size, test.scale)
+		if err != nil {
+			t.Errorf("zd.Illustration(%d, %d) returned `%v`", test.size, test.scale, err)
+			continue
+		}
+		if len(illustration) != test.length || !bytes.HasPrefix(illustration, pngSignature) {
+			t.Errorf("zd.Illustration(%d, %d) returned %d bytes; want a PNG of %d bytes",
+				test.size, test.scale, len(illustration), test.length)
+		}
+	}
+	if _, err := zd.Illustration(96, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("zd.Illustration(96, 1) returned `%v`; want `%s`", err, ErrNotFound)
+	}
+	if _, err := zd.MetadataBytes("Missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("zd.MetadataBytes(Missing) returned `%v`; want `%s`", err, ErrNotFound)
+	}
+	if value := zd.MetadataFor("Missing"); value != "" {
+		t.Errorf("zd.MetadataFor(Missing) returned `%s`", value)
+	}
+
+	// values larger than 32MB are not read into memory
+	var zc = hugeBlobFile(t, []writerTestEntry{writerTestEntries[0], {NamespaceZimMetadata, "LongDescription", "",
+		"text/plain", "huge", "", false}}, 1)
+	if entry, _, err := zc.EntryWithURL(NamespaceZimMetadata, []byte("LongDescription")); err != nil || entry.BlobNumber() != 1 {
+		t.Fatalf("LongDescription is %s, `%v`; want it at blob 1", entry.String(), err)
+	}
+	if value, err := zc.MetadataBytes("LongDescription"); !errors.Is(err, ErrInvalidBlob) {
+		t.Errorf("zc.MetadataBytes(LongDescription) returned %d bytes, `%v`; want `%s`", len(value), err, ErrInvalidBlob)
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	var zd, openErr = Open(path.Join("testdata", filenameMetadata))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zd.Close()
+	if violations := zd.ValidateMetadata(); len(violations) > 0 {
+		t.Errorf("zd.ValidateMetadata() returned %v for valid metadata", violations)
+	}
+
+	var valid = map[string]string{
+		"Name":                 "test_en_all",
+		"Title":                "Test",
+		"Language":             "eng",
+		"Creator":              "Creator",
+		"Publisher":            "Publisher",
+		"Date":                 "2024-01-31",
+		"Description":          "A test",
+		"Illustration_48x48@1": string(pngSignature) + "data",
+	}
+	var tests = []struct {
+		changes  map[string]string // an empty value removes the key
+		expected []MetadataViolation
+	}{
+		{nil, nil},
+		{map[string]string{"Title": "", "Illustration_48x48@1": ""}, []MetadataViolation{
+			{"Title", SeverityError, "mandatory value is missing"},
+			{"Illustration_48x48@1", SeverityError, "mandatory value is missing"}}},
+		{map[string]string{"Title": strings.Repeat("ä", 31), "Description": " "}, []MetadataViolation{
+			{"Description", SeverityError, "mandatory value is empty"},
+			{"Title", SeverityError, "has 31 characters; at most 30 are allowed"}}},
+		{map[string]string{"Description": strings.Repeat("d", 81), "LongDescription": "long"}, []MetadataViolation{
+			{"Description", SeverityError, "has 81 characters; at most 80 are allowed"},
+			{"LongDescription", SeverityWarning, "is not longer than Description"}}},
+		{map[string]string{"Language": "eng,de,ENG", "Date": "31.01.2024"}, []MetadataViolation{
+			{"Language", SeverityError, "`de` is no ISO 639-3 language code"},
+			{"Language", SeverityError, "`ENG` is no ISO 639-3 language code"},
+			{"Date", SeverityError, "`31.01.2024` has not the format YYYY-MM-DD"}}},
+		{map[string]string{"Tags": "wikipedia;;_pictures:maybe;_category:; x;_foo:bar;_videos:no"}, []MetadataViolation{
+			{"Tags", SeverityWarning, "contains an empty tag"},
+			{"Tags", SeverityError, "tag `_pictures:maybe` must have the value yes or no"},
+			{"Tags", SeverityError, "tag `_category:` has no value"},
+			{"Tags", SeverityWarning, "tag ` x` has surrounding spaces"},
+			{"Tags", SeverityWarning, "tag `_foo:bar` is no known special tag"}}},
+		{map[string]string{"Illustration_48x48@1": "GIF89a"}, []MetadataViolation{
+			{"Illustration_48x48@1", SeverityError, "is no PNG image"}}},
+	}
+	for i, test := range tests {
+		var zt = &File{metadata: make(map[string]string)}
+		for key, value := range valid {
+			zt.metadata[key] = value
+		}
+		for key, value := range test.changes {
+			if value == "" {
+				delete(zt.metadata, key)
+			} else {
+				zt.metadata[key] = value
+			}
+		}
+		for key := range zt.metadata {
+			zt.metadataKeys = append(zt.metadataKeys, key)
+		}
+		sort.Strings(zt.metadataKeys)
+		var violations = zt.ValidateMetadata()
+		if fmt.Sprint(violations) != fmt.Sprint(test.expected) {
+			t.Errorf("test %d: ValidateMetadata() returned\n%v; want\n%v", i, violations, test.expected)
+		}
+	}
+	if s := (MetadataViolation{"Date", SeverityError, "invalid"}).String(); s != "error: Date: invalid" {
+		t.Errorf("MetadataViolation.String() returned `%s`", s)
+	}
+}
+
+func TestTypedMetadata(t *testing.T) {
+	var zd, openErr = Open(path.Join("testdata", filenameMetadata))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zd.Close()
+
+	if languages := zd.Languages(); strings.Join(languages, ",") != "eng,deu" {
+		t.Errorf("zd.Languages() was %v; want [eng deu]", languages)
+	}
+	if counter := zd.Counter(); len(counter) != 1 || counter["text/html"] != 1 {
+		t.Errorf("zd.Counter() was %v; want map[text/html:1]", counter)
+	}
+	var tags = zd.Tags()
+	if strings.Join(tags.Plain, ",") != "wikipedia" || tags.Category() != "wikipedia" {
+		t.Errorf("zd.Tags() was %+v", tags)
+	}
+	if tags.Pictures() || tags.Videos() || !tags.Details() || !tags.FTIndex() {
+		t.Errorf("flags of zd.Tags() were wrong: %+v", tags)
+	}
+
+	// a file without these metadata
+	var ze = &File{metadata: map[string]string{}}
+	if languages, counter, tags := ze.Languages(), ze.Counter(), ze.Tags(); len(languages) != 0 || counter == nil ||
+		len(counter) != 0 || len(tags.Plain) != 0 || len(tags.Special) != 0 {
+		t.Errorf("ze.Languages(), ze.Counter() and ze.Tags() returned %v, %v, %+v", languages, counter, tags)
+	}
+
+	tags = parseTags(" nopic ; _ftindex;;_sw:yes;stackexchange")
+	if strings.Join(tags.Plain, ",") != "nopic,stackexchange" || tags.Special["sw"] != "yes" {
+		t.Errorf("parseTags() returned %+v", tags)
+	}
+	if !tags.Pictures() || !tags.FTIndex() || tags.Category() != "" {
+		t.Errorf("flags of parseTags() were wrong: %+v", tags)
+	}
+
+	var zc = &File{metadata: map[string]string{"Counter": "text/html=12;image/png=3;broken;=4;image/jpeg=x;text/html=1"}}
+	if counter := zc.Counter(); fmt.Sprint(counter) != "map[image/png:3 text/html:13]" {
+		t.Errorf("zc.Counter() was %v", counter)
+	}
+}
@@ -0,0 +1,171 @@
+package zim
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Severity is the severity of a MetadataViolation.
+type Severity uint8
+
+const (
+	// SeverityWarning marks metadata, which doesn't follow a recommendation of the ZIM metadata specification.
+	SeverityWarning Severity = iota
+	// SeverityError marks metadata, which violates a requirement of the ZIM metadata specification.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", uint8(s))
+}
+
+// MetadataViolation describes a metadata value, which doesn't conform to the ZIM metadata specification.
+type MetadataViolation struct {
+	Key      string // the metadata key, e.g. "Title"
+	Severity Severity
+	Message  string
+}
+
+func (v MetadataViolation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Severity, v.Key, v.Message)
+}
+
+// mandatoryMetadata are the keys every ZIM file must have.
+var mandatoryMetadata = [...]string{"Name", "Title", "Language", "Creator", "Publisher", "Date", "Description",
+	defaultIllustration}
+
+// defaultIllustration is the key of the illustration every ZIM file must have.
+const defaultIllustration = "Illustration_48x48@1"
+
+// Maximum lengths in characters of metadata values.
+const (
+	maxTitleLength           = 30
+	maxDescriptionLength     = 80
+	maxLongDescriptionLength = 4000
+)
+
+// booleanTags are the special tags, whose value is "yes" or "no".
+var booleanTags = map[string]bool{"_pictures": true, "_videos": true, "_details": true, "_ftindex": true}
+
+// otherSpecialTags are the special tags with any value.
+var otherSpecialTags = map[string]bool{"_category": true, "_sw": true}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// ValidateMetadata checks the metadata against the ZIM metadata specification:
+// the mandatory keys Name, Title, Language, Creator, Publisher, Date, Description and
+// Illustration_48x48@1 must be set, Language must be a comma separated list of ISO 639-3 codes,
+// Date must have the format YYYY-MM-DD, Title, Description and LongDescription must not be
+// longer than 30, 80 and 4000 characters, Tags must be a semicolon separated list and
+// the illustration must be a PNG image.
+// The violations are returned in the order of the checks; nil means the metadata conforms.
+// Note: the codes of Language are checked for their syntax, not against the list of ISO 639-3 codes.
+func (z *File) ValidateMetadata() []MetadataViolation {
+	var violations []MetadataViolation
+	var report = func(key string, severity Severity, format string, args ...interface{}) {
+		violations = append(violations, MetadataViolation{Key: key, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	var metadata = z.Metadata()
+	var keys = z.MetadataKeys()
+	var isSet = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isSet[key] = true
+	}
+
+	for _, key := range mandatoryMetadata {
+		if !isSet[key] {
+			report(key, SeverityError, "mandatory value is missing")
+		} else if value, small := metadata[key]; small && strings.TrimSpace(value) == "" {
+			report(key, SeverityError, "mandatory value is empty")
+		}
+	}
+
+	for _, limit := range [...]struct {
+		key       string
+		maxLength int
+	}{
+		{"Title", maxTitleLength},
+		{"Description", maxDescriptionLength},
+		{"LongDescription", maxLongDescriptionLength},
+	} {
+		if !isSet[limit.key] {
+			continue
+		}
+		if length := utf8.RuneCountInString(z.MetadataFor(limit.key)); length > limit.maxLength {
+			report(limit.key, SeverityError, "has %d characters; at most %d are allowed", length, limit.maxLength)
+		}
+	}
+	if isSet["LongDescription"] && isSet["Description"] &&
+		utf8.RuneCountInString(z.MetadataFor("LongDescription")) <= utf8.RuneCountInString(metadata["Description"]) {
+		report("LongDescription", SeverityWarning, "is not longer than Description")
+	}
+
+	if language, found := metadata["Language"]; found && strings.TrimSpace(language) != "" {
+		for _, code := range strings.Split(language, ",") {
+			if !isLanguageCode(code) {
+				report("Language", SeverityError, "`%s` is no ISO 639-3 language code", code)
+			}
+		}
+	}
+
+	if date, found := metadata["Date"]; found && strings.TrimSpace(date) != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			report("Date", SeverityError, "`%s` has not the format YYYY-MM-DD", date)
+		}
+	}
+
+	if tags, found := metadata["Tags"]; found {
+		for _, tag := range strings.Split(tags, ";") {
+			var name, value, hasValue = strings.Cut(tag, ":")
+			switch {
+			case strings.TrimSpace(tag) == "":
+				report("Tags", SeverityWarning, "contains an empty tag")
+			case tag != strings.TrimSpace(tag):
+				report("Tags", SeverityWarning, "tag `%s` has surrounding spaces", tag)
+			case booleanTags[name]:
+				if value != "yes" && value != "no" {
+					report("Tags", SeverityError, "tag `%s` must have the value yes or no", tag)
+				}
+			case otherSpecialTags[name]:
+				if !hasValue || value == "" {
+					report("Tags", SeverityError, "tag `%s` has no value", tag)
+				}
+			case strings.HasPrefix(name, "_"):
+				report("Tags", SeverityWarning, "tag `%s` is no known special tag", tag)
+			}
+		}
+	}
+
+	if isSet[defaultIllustration] {
+		var illustration, err = z.MetadataBytes(defaultIllustration)
+		if err != nil {
+			report(defaultIllustration, SeverityError, "reading failed: %s", err)
+		} else if !bytes.HasPrefix(illustration, pngSignature) {
+			report(defaultIllustration, SeverityError, "is no PNG image")
+		}
+	}
+
+	return violations
+}
+
+// isLanguageCode reports whether code has the syntax of an ISO 639-3 code: three lowercase letters.
+func isLanguageCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'a' || code[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,10 @@
 package zim
 
-import "strings"
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
 
 // Mimetype describes one of the three possible
 // fixed Mimetypes for a Directory Entry.
@@ -13,12 +17,15 @@ const (
 	MimetypeRedirectEntry = Mimetype(0xFFFF)
 )
 
-func (z *File) readMimetypeList() {
-	seek(z.f, int64(z.header.mimeListPos))
+func (z *File) readMimetypeList() error {
+	var r = z.dataReaderAt(int64(z.header.mimeListPos))
 	for {
-		var mimetype = readNullTerminatedString(z.f)
+		var mimetype, readErr = readNullTerminatedString(r)
+		if readErr != nil {
+			return readError("read mimetype list", int64(z.header.mimeListPos), readErr, ErrInvalidMimetypeList)
+		}
 		if len(mimetype) == 0 {
-			break
+			return nil
 		}
 		z.mimetypeList = append(z.mimetypeList, strings.ToLower(strings.TrimSpace(mimetype)))
 	}
@@ -28,3 +35,46 @@ func (z *File) readMimetypeList() {
 func (z *File) MimetypeList() []string {
 	return z.mimetypeList
 }
+
+// MimetypeOf returns the Mimetype of the Directory Entry as found in the Mimetype list.
+// Redirect Entries, LinkTargets and Deleted Entries have no Mimetype in the list.
+// For them and for indices out of range of the list the returned error is ErrInvalidMimetype.
+func (z *File) MimetypeOf(e *DirectoryEntry) (string, error) {
+	if int(e.mimetype) >= len(z.mimetypeList) {
+		return "", fmt.Errorf("%w: index %d of %s", ErrInvalidMimetype, e.mimetype, e.String())
+	}
+	return z.mimetypeList[e.mimetype], nil
+}
+
+// MimetypeIndex returns the index of the Mimetype in the Mimetype list, which is
+// compared to the Directory Entries' Mimetype(). Like the list, the Mimetype is compared
+// in lower case without surrounding spaces. found is false if the list doesn't contain it.
+func (z *File) MimetypeIndex(mimetype string) (index Mimetype, found bool) {
+	mimetype = strings.ToLower(strings.TrimSpace(mimetype))
+	for i, listed := range z.mimetypeList {
+		if listed == mimetype {
+			return Mimetype(i), true
+		}
+	}
+	return 0, false
+}
+
+// EntriesWithMimetype returns an iterator over the Directory Entries with the Mimetype in URL order.
+// Redirects are not followed, so Redirect Entries are never returned.
+// If reading fails, the error is yielded with an empty Directory Entry and the iteration stops.
+func (z *File) EntriesWithMimetype(mimetype string) iter.Seq2[DirectoryEntry, error] {
+	return func(yield func(DirectoryEntry, error) bool) {
+		var index, found = z.MimetypeIndex(mimetype)
+		if !found {
+			return
+		}
+		for entry, err := range z.Entries(0) {
+			if err == nil && entry.mimetype != index {
+				continue
+			}
+			if !yield(entry, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package zim
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -24,3 +25,68 @@ func TestMimetypeList(t *testing.T) {
 		t.Errorf("z.MimetypeList() was `%s`; want `%s`", s1, s2)
 	}
 }
+
+func TestMimetypeHelpers(t *testing.T) {
+	for _, test := range []struct {
+		mimetype string
+		index    Mimetype
+		found    bool
+	}{
+		{"application/javascript", 0, true},
+		{"image/png", 4, true},
+		{" Text/HTML ", 7, true},
+		{"webm", 9, true},
+		{"video/mp4", 0, false},
+	} {
+		if index, found := z.MimetypeIndex(test.mimetype); index != test.index || found != test.found {
+			t.Errorf("z.MimetypeIndex(%q) returned %d, %t; want %d, %t", test.mimetype, index, found, test.index, test.found)
+		}
+	}
+
+	var counts = make(map[string]int)
+	var redirects int
+	for entry, err := range z.Entries(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mimetype, mimetypeErr = z.MimetypeOf(&entry)
+		if entry.IsRedirect() {
+			redirects++
+			if !errors.Is(mimetypeErr, ErrInvalidMimetype) {
+				t.Errorf("z.MimetypeOf(%s) returned `%v`; want `%s`", entry.String(), mimetypeErr, ErrInvalidMimetype)
+			}
+			continue
+		}
+		if mimetypeErr != nil || mimetype != z.MimetypeList()[entry.Mimetype()] {
+			t.Errorf("z.MimetypeOf(%s) returned `%s`, `%v`", entry.String(), mimetype, mimetypeErr)
+		}
+		counts[mimetype]++
+	}
+	if redirects == 0 || counts["image/png"] == 0 {
+		t.Fatalf("test file has %d redirects and %d PNG images", redirects, counts["image/png"])
+	}
+
+	for _, mimetype := range z.MimetypeList() {
+		var count int
+		for entry, err := range z.EntriesWithMimetype(mimetype) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found, _ := z.MimetypeOf(&entry); found != mimetype {
+				t.Errorf("z.EntriesWithMimetype(%s) returned %s with Mimetype %s", mimetype, entry.String(), found)
+			}
+			count++
+		}
+		if count != counts[mimetype] {
+			t.Errorf("z.EntriesWithMimetype(%s) returned %d Directory Entries; want %d", mimetype, count, counts[mimetype])
+		}
+	}
+	for range z.EntriesWithMimetype("video/mp4") {
+		t.Error("z.EntriesWithMimetype(video/mp4) returned a Directory Entry")
+	}
+
+	var invalid = DirectoryEntry{mimetype: Mimetype(len(z.MimetypeList()))}
+	if _, err := z.MimetypeOf(&invalid); !errors.Is(err, ErrInvalidMimetype) {
+		t.Errorf("z.MimetypeOf() for an index out of range returned `%v`; want `%s`", err, ErrInvalidMimetype)
+	}
+}
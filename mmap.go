@@ -0,0 +1,53 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// mappedFile is the contents of a memory mapped ZIM file. Reading it needs no
+// system calls and the uncompressed parts of the file are used without copying them.
+type mappedFile []byte
+
+func (m mappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("zim: negative offset")
+	}
+	if off >= int64(len(m)) {
+		return 0, io.EOF
+	}
+	var n = copy(p, m[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// slice returns n bytes at the offset without copying them.
+// ok is false, if the bytes are not inside of the file.
+func (m mappedFile) slice(off, n int64) (data []byte, ok bool) {
+	if off < 0 || n < 0 || off > int64(len(m)) || n > int64(len(m))-off {
+		return nil, false
+	}
+	return m[off : off+n : off+n], true
+}
+
+// mappedSection returns n bytes at the offset of the section, if the section is part of a memory mapped file.
+func mappedSection(section *io.SectionReader, off, n int64) ([]byte, bool) {
+	var outer, base, _ = section.Outer()
+	var m, mapped = outer.(mappedFile)
+	if !mapped || off < 0 || n < 0 || n > section.Size()-off {
+		return nil, false
+	}
+	return m.slice(base+off, n)
+}
+
+// sectionReader returns a reader for n bytes at the offset of the section,
+// which is a bytes.Reader over the mapping for memory mapped files.
+func sectionReader(section *io.SectionReader, off, n int64) io.Reader {
+	if data, mapped := mappedSection(section, off, n); mapped {
+		return bytes.NewReader(data)
+	}
+	return io.NewSectionReader(section, off, n)
+}
@@ -0,0 +1,51 @@
+package zim
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// munmapCloser unmaps a memory mapped file.
+type munmapCloser []byte
+
+func (c munmapCloser) Close() error { return syscall.Munmap(c) }
+
+// OpenMmap opens the file like Open, but maps it into memory.
+// Pointer lists, Directory Entries and uncompressed clusters are read from the
+// mapping without system calls and without copying them, so the URL and Title
+// of Directory Entries, readers of uncompressed blobs and uncompressed Clusters
+// must not be used after Close. Split ZIM files are opened by Open without a mapping.
+func OpenMmap(filename string) (*File, error) {
+	if _, split := splitBasename(filename); split {
+		return Open(filename)
+	}
+	var f, fileErr = os.Open(filename)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	// the mapping stays valid after the file is closed
+	defer f.Close()
+	var fileInfo, statErr = f.Stat()
+	if statErr != nil {
+		return nil, statErr
+	}
+	var size = fileInfo.Size()
+	if size == 0 {
+		return OpenReaderAt(mappedFile(nil), 0)
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("zim: %s is too large to be memory mapped", filename)
+	}
+	var data, mmapErr = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if mmapErr != nil {
+		return nil, &os.PathError{Op: "mmap", Path: filename, Err: mmapErr}
+	}
+	var result, openErr = OpenReaderAt(mappedFile(data), size)
+	if openErr != nil {
+		syscall.Munmap(data)
+		return nil, openErr
+	}
+	result.closer = munmapCloser(data)
+	return result, nil
+}
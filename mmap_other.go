@@ -0,0 +1,9 @@
+//go:build !linux
+
+package zim
+
+// OpenMmap opens the file like Open. Memory mapping is only supported on Linux,
+// on other systems the file is read with positioned reads.
+func OpenMmap(filename string) (*File, error) {
+	return Open(filename)
+}
@@ -0,0 +1,124 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"runtime"
+	"testing"
+)
+
+func TestOpenMmap(t *testing.T) {
+	for _, filename := range []string{filenameTestfile, filenameMixedCompression} {
+		var zf, openErr = Open(path.Join("testdata", filename))
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		defer zf.Close()
+		var zm, mmapErr = OpenMmap(path.Join("testdata", filename))
+		if mmapErr != nil {
+			t.Fatal(mmapErr)
+		}
+		defer zm.Close()
+
+		if err := zm.ValidateChecksum(); err != nil {
+			t.Errorf("%s: %s", filename, err)
+		}
+		if zm.Title() != zf.Title() || len(zm.MimetypeList()) != len(zf.MimetypeList()) {
+			t.Errorf("%s: metadata of OpenMmap() differs from Open()", filename)
+		}
+		for position := uint32(0); position < zf.ArticleCount(); position++ {
+			var expected, _ = zf.EntryAtURLPosition(position)
+			var entry, entryErr = zm.EntryAtURLPosition(position)
+			if entryErr != nil {
+				t.Fatalf("%s: %s", filename, entryErr)
+			}
+			if entry.String() != expected.String() || entry.Mimetype() != expected.Mimetype() ||
+				entry.ClusterNumber() != expected.ClusterNumber() || entry.BlobNumber() != expected.BlobNumber() {
+				t.Errorf("%s: zm.EntryAtURLPosition(%d) returned %s; want %s", filename, position, entry.String(), expected.String())
+			}
+		}
+		for position := uint32(0); position < zf.ClusterCount(); position++ {
+			var expected, _ = zf.ClusterAt(position)
+			var cluster, clusterErr = zm.ClusterAt(position)
+			if clusterErr != nil {
+				t.Fatalf("%s: %s", filename, clusterErr)
+			}
+			if !bytes.Equal(cluster.data, expected.data) {
+				t.Errorf("%s: zm.ClusterAt(%d) returned different data", filename, position)
+			}
+		}
+	}
+
+	var zm, _ = OpenMmap(path.Join("testdata", filenameMixedCompression))
+	defer zm.Close()
+	// cluster 0 is uncompressed, cluster 2 zstd compressed
+	for _, clusterPosition := range []uint32{0, 2} {
+		var reader, blobSize, readerErr = zm.BlobReaderAt(clusterPosition, 0)
+		if readerErr != nil {
+			t.Fatal(readerErr)
+		}
+		var data, _ = ioutil.ReadAll(reader)
+		if int64(len(data)) != blobSize || blobSize == 0 {
+			t.Errorf("zm.BlobReaderAt(%d, 0) returned %d bytes; want %d", clusterPosition, len(data), blobSize)
+		}
+		var _, isBytesReader = reader.(*bytes.Reader)
+		if expected := runtime.GOOS == "linux" && clusterPosition == 0; isBytesReader != expected {
+			t.Errorf("zm.BlobReaderAt(%d, 0) returned %T", clusterPosition, reader)
+		}
+	}
+
+	var corrupted = corruptedTestfile(t, func(data []byte) []byte { return data[:50] })
+	if _, err := OpenMmap(corrupted); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("OpenMmap() of a truncated file returned `%v`; want `%s`", err, ErrInvalidHeader)
+	}
+}
+
+func benchmarkOpeners(b *testing.B, filename string, bench func(b *testing.B, zb *File)) {
+	for name, open := range map[string]func(string) (*File, error){"Open": Open, "OpenMmap": OpenMmap} {
+		b.Run(name, func(b *testing.B) {
+			var zb, err = open(path.Join("testdata", filename))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer zb.Close()
+			b.ResetTimer()
+			bench(b, zb)
+		})
+	}
+}
+
+func BenchmarkEntryWithURL(b *testing.B) {
+	benchmarkOpeners(b, filenameTestfile, func(b *testing.B, zb *File) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := zb.EntryWithURL(NamespaceArticles, []byte("index.htm")); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkEntryAtTitlePosition(b *testing.B) {
+	benchmarkOpeners(b, filenameTestfile, func(b *testing.B, zb *File) {
+		for i := 0; i < b.N; i++ {
+			if _, err := zb.EntryAtTitlePosition(uint32(i) % zb.ArticleCount()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkUncompressedBlobReader(b *testing.B) {
+	benchmarkOpeners(b, filenameMixedCompression, func(b *testing.B, zb *File) {
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			var reader, _, err = zb.BlobReaderAt(0, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf.Reset()
+			buf.ReadFrom(reader)
+		}
+	})
+}
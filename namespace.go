@@ -18,3 +18,18 @@ const (
 )
 
 func (n Namespace) String() string { return string(n) }
+
+// Namespaces of ZIM files with the new namespace scheme, see File.HasNewNamespaceScheme.
+// The metadata is still stored in NamespaceZimMetadata.
+const (
+	NamespaceContent   = Namespace('C') // all contents: articles, images, stylesheets, scripts, ...
+	NamespaceWellKnown = Namespace('W') // well known entries, eg. the redirect mainPage
+	NamespaceIndexes   = Namespace('X') // title listings and fulltext indexes
+)
+
+// HasNewNamespaceScheme reports whether the ZIM file uses the namespace scheme of
+// libzim 7 and newer (minor version >= 1), which stores all contents in NamespaceContent.
+// Older files store them in NamespaceArticles, NamespaceImagesFiles, NamespaceLayout, etc.
+func (z *File) HasNewNamespaceScheme() bool {
+	return z.header.majorVersion >= 6 && z.header.minorVersion >= 1
+}
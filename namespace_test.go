@@ -0,0 +1,111 @@
+package zim
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+const filenameNewNamespace = "new_namespace.zim"
+
+func TestNewNamespaceScheme(t *testing.T) {
+	var zn, openErr = Open(path.Join("testdata", filenameNewNamespace))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zn.Close()
+
+	if !zn.HasNewNamespaceScheme() {
+		t.Error("zn.HasNewNamespaceScheme() returned false for a ZIM 6.1 file")
+	}
+	if z.HasNewNamespaceScheme() {
+		t.Error("z.HasNewNamespaceScheme() returned true for a ZIM 5 file")
+	}
+	if title := zn.Title(); title != "New namespace test" {
+		t.Errorf("zn.Title() was `%s`; want `%s`", title, "New namespace test")
+	}
+
+	// the header specifies no main page, so W/mainPage is used
+	var mainPage, mainPageErr = zn.MainPage()
+	if mainPageErr != nil {
+		t.Fatal(mainPageErr)
+	}
+	if mainPage.Namespace() != NamespaceContent || string(mainPage.URL()) != "index.html" {
+		t.Errorf("zn.MainPage() returned %s; want C/index.html", mainPage.String())
+	}
+	if !mainPage.IsArticle() {
+		t.Error("mainPage.IsArticle() returned false for a content entry")
+	}
+
+	var favicon, faviconErr = zn.Favicon()
+	if faviconErr != nil {
+		t.Fatal(faviconErr)
+	}
+	if favicon.Namespace() != NamespaceZimMetadata || string(favicon.URL()) != "Illustration_48x48@1" {
+		t.Errorf("zn.Favicon() returned %s; want M/Illustration_48x48@1", favicon.String())
+	}
+
+	var tests = []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{"Foo", "<p>new namespace paragraph</p>", nil},
+		{"assets/style.css", "p { margin: 0; }", nil},
+		{"Bar", "", nil}, // redirect
+		{"C/Foo", "", ErrNotFound},
+		{"Title", "", ErrNotFound},
+	}
+	for _, test := range tests {
+		var entry, _, entryErr = zn.EntryWithPath(test.path)
+		if !errors.Is(entryErr, test.err) {
+			t.Errorf("zn.EntryWithPath(%q) returned `%v`; want `%v`", test.path, entryErr, test.err)
+			continue
+		}
+		if entryErr != nil || len(test.expected) == 0 {
+			continue
+		}
+		var reader, _, readerErr = zn.BlobReader(&entry)
+		if readerErr != nil {
+			t.Fatal(readerErr)
+		}
+		if data, _ := ioutil.ReadAll(reader); !strings.Contains(string(data), test.expected) {
+			t.Errorf("content of %q doesn't contain `%s`", test.path, test.expected)
+		}
+	}
+}
+
+func TestEntryWithPathOldNamespaceScheme(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	var tests = []struct {
+		path      string
+		namespace Namespace
+		url       string
+	}{
+		{"index.html", NamespaceArticles, "index.html"},
+		{"A/index.html", NamespaceArticles, "index.html"},
+		{"style.css", NamespaceLayout, "style.css"},
+		{"-/style.css", NamespaceLayout, "style.css"},
+		{"M/Title", NamespaceZimMetadata, "Title"},
+	}
+	for _, test := range tests {
+		var entry, _, entryErr = zm.EntryWithPath(test.path)
+		if entryErr != nil {
+			t.Errorf("zm.EntryWithPath(%q) returned `%s`", test.path, entryErr)
+			continue
+		}
+		if entry.Namespace() != test.namespace || string(entry.URL()) != test.url {
+			t.Errorf("zm.EntryWithPath(%q) returned %s; want %s/%s", test.path, entry.String(), test.namespace, test.url)
+		}
+	}
+	if _, _, err := zm.EntryWithPath("Title"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("zm.EntryWithPath(\"Title\") returned `%v`; want `%s`", err, ErrNotFound)
+	}
+}
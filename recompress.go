@@ -0,0 +1,288 @@
+package zim
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Recompress writes a copy of the ZIM file to w, whose clusters are packed anew with the
+// compression, compression level and cluster size of the options. The clusters are read one by one
+// with ClusterAt, the blobs of clusters larger than 32MB one by one. Blobs of compressed clusters are compressed with options.Compression,
+// the others stay uncompressed; blobs without a Directory Entry are dropped.
+// All Directory Entries, the main page and the layout page are kept, while the pointerlists,
+// the Mimetype list and the checksum are written anew. The version of the file is kept,
+// so options.MajorVersion and options.MinorVersion are ignored. If options.UUID is nil,
+// the copy gets a random UUID; use z.UUID() to keep the UUID of the file.
+func (z *File) Recompress(w io.Writer, options WriterOptions) error {
+	options.MajorVersion, options.MinorVersion = z.Version()
+	var zw, err = NewWriter(w, options)
+	if err != nil {
+		return err
+	}
+	zw.copied = true
+	if err = z.copyEntries(zw, nil, nil); err != nil {
+		zw.Abort()
+		return err
+	}
+	return zw.Close()
+}
+
+// copyEntries adds the Directory Entries and blobs of the file to the Writer. Only the Directory Entries
+// at the URL positions, which are true in keep, are copied, or all of them if keep is nil; the targets
+// of copied redirects must be copied too. The URL positions, which are true in frontArticles,
+// are front articles of the copy.
+func (z *File) copyEntries(zw *Writer, keep, frontArticles []bool) error {
+	var entries = make([]*writerEntry, z.header.articleCount) // nil if the Directory Entry is not copied
+	var redirectIndex = make(map[*writerEntry]uint32)
+	// the positions of the blobs in z; cluster and blob of the writerEntry are set by the Writer
+	type sourceBlob struct {
+		e             *writerEntry
+		cluster, blob uint32
+	}
+	var contents []sourceBlob
+	var position uint32
+	for entry, err := range z.Entries(0) {
+		if err != nil {
+			return err
+		}
+		position++
+		if keep != nil && !keep[position-1] {
+			continue
+		}
+		var e = &writerEntry{
+			namespace:    entry.namespace,
+			url:          string(entry.url),
+			title:        string(entry.title),
+			parameter:    entry.parameter,
+			revision:     entry.revision,
+			frontArticle: frontArticles != nil && frontArticles[position-1],
+		}
+		switch {
+		case entry.IsRedirect():
+			e.redirect = true
+			redirectIndex[e] = entry.RedirectIndex()
+		case entry.IsLinkTarget(), entry.IsDeletedEntry():
+			e.special = entry.mimetype
+		default:
+			var mimetype, mimetypeErr = z.MimetypeOf(&entry)
+			if mimetypeErr != nil {
+				return fmt.Errorf("%s: %w", entry.String(), mimetypeErr)
+			}
+			e.mimetype = mimetype
+			contents = append(contents, sourceBlob{e, entry.clusterNumber, entry.BlobNumber()})
+		}
+		entries[position-1] = e
+	}
+	for e, index := range redirectIndex {
+		if entries[index] == nil {
+			return fmt.Errorf("%w: target of redirect %s is not copied", ErrNotFound, entryPath(e.namespace, e.url))
+		}
+		e.target = entryPath(entries[index].namespace, entries[index].url)
+	}
+	var pathAt = func(position uint32) string {
+		if position < uint32(len(entries)) && entries[position] != nil {
+			return entryPath(entries[position].namespace, entries[position].url)
+		}
+		return ""
+	}
+	zw.mainPage, zw.layoutPage = pathAt(z.header.mainPage), pathAt(z.header.layoutPage)
+
+	// the blobs are copied in the order of the clusters and blobs of the file;
+	// Directory Entries sharing a blob share it in the copy too
+	sort.SliceStable(contents, func(i, j int) bool {
+		if contents[i].cluster != contents[j].cluster {
+			return contents[i].cluster < contents[j].cluster
+		}
+		return contents[i].blob < contents[j].blob
+	})
+	var cluster *sourceCluster
+	for i := 0; i < len(contents); {
+		var clusterPosition, blobPosition = contents[i].cluster, contents[i].blob
+		if i == 0 || contents[i-1].cluster != clusterPosition {
+			var err error
+			if cluster, err = z.readSourceCluster(clusterPosition); err != nil {
+				return err
+			}
+		}
+		var blob, err = cluster.blobAt(blobPosition)
+		if err != nil {
+			return fmt.Errorf("cluster %d blob %d: %w", clusterPosition, blobPosition, err)
+		}
+		var compression = CompressionNone
+		if cluster.compressed {
+			compression = zw.options.Compression
+		}
+		var first = contents[i].e
+		var fill = func(b *bytes.Buffer) error { _, err := b.Write(blob); return err }
+		if err = zw.addBlob(first, compression, fill); err != nil {
+			return err
+		}
+		for i++; i < len(contents) && contents[i].cluster == clusterPosition &&
+			contents[i].blob == blobPosition; i++ {
+			contents[i].e.cluster, contents[i].e.blob = first.cluster, first.blob
+		}
+	}
+
+	for _, e := range entries {
+		if e == nil {
+			continue
+		}
+		if err := zw.add(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareEntries compares the file entry by entry with the other file, like a copy written by
+// Recompress. Both files must have the same Directory Entries in URL order with the same titles,
+// parameters, revisions, Mimetypes, redirect targets and contents, and the same main page and layout page.
+// The returned error wraps ErrEntryMismatch for the first difference.
+func (z *File) CompareEntries(other *File) error {
+	if z.header.articleCount != other.header.articleCount {
+		return fmt.Errorf("%w: %d and %d Directory Entries", ErrEntryMismatch,
+			z.header.articleCount, other.header.articleCount)
+	}
+	if z.header.mainPage != other.header.mainPage || z.header.layoutPage != other.header.layoutPage {
+		return fmt.Errorf("%w: different main page or layout page", ErrEntryMismatch)
+	}
+	type blobPair struct {
+		entry                   DirectoryEntry
+		otherCluster, otherBlob uint32
+	}
+	var contents []blobPair
+	var position uint32
+	for entry, err := range z.Entries(0) {
+		if err != nil {
+			return err
+		}
+		var otherEntry, otherErr = other.EntryAtURLPosition(position)
+		if otherErr != nil {
+			return otherErr
+		}
+		position++
+		var mismatch = func(what string) error {
+			return fmt.Errorf("%w: %s of %s", ErrEntryMismatch, what, entry.String())
+		}
+		switch {
+		case entry.namespace != otherEntry.namespace || !bytes.Equal(entry.url, otherEntry.url):
+			return fmt.Errorf("%w: %s and %s", ErrEntryMismatch, entry.String(), otherEntry.String())
+		case !bytes.Equal(entry.Title(), otherEntry.Title()):
+			return mismatch("title")
+		case !bytes.Equal(entry.parameter, otherEntry.parameter):
+			return mismatch("parameter")
+		case entry.revision != otherEntry.revision:
+			return mismatch("revision")
+		case entry.IsRedirect() != otherEntry.IsRedirect() || entry.IsLinkTarget() != otherEntry.IsLinkTarget() ||
+			entry.IsDeletedEntry() != otherEntry.IsDeletedEntry():
+			return mismatch("kind")
+		case entry.IsRedirect():
+			if entry.RedirectIndex() != otherEntry.RedirectIndex() {
+				return mismatch("redirect target")
+			}
+		case entry.IsLinkTarget(), entry.IsDeletedEntry():
+		default:
+			var mimetype, mimetypeErr = z.MimetypeOf(&entry)
+			var otherMimetype, otherMimetypeErr = other.MimetypeOf(&otherEntry)
+			if mimetypeErr != nil || otherMimetypeErr != nil || mimetype != otherMimetype {
+				return mismatch("mimetype")
+			}
+			contents = append(contents, blobPair{entry, otherEntry.clusterNumber, otherEntry.BlobNumber()})
+		}
+	}
+
+	// the contents are compared in the cluster order of the file; the last two clusters of the other
+	// file are kept, since a copy written by Recompress fills a compressed and an uncompressed cluster
+	// at the same time
+	sort.SliceStable(contents, func(i, j int) bool {
+		if contents[i].entry.clusterNumber != contents[j].entry.clusterNumber {
+			return contents[i].entry.clusterNumber < contents[j].entry.clusterNumber
+		}
+		return contents[i].entry.BlobNumber() < contents[j].entry.BlobNumber()
+	})
+	var cluster *sourceCluster
+	var otherClusters [2]*sourceCluster
+	var otherClusterAt = func(clusterPosition uint32) (*sourceCluster, error) {
+		for _, c := range otherClusters {
+			if c != nil && c.position == clusterPosition {
+				return c, nil
+			}
+		}
+		var c, err = other.readSourceCluster(clusterPosition)
+		if err == nil {
+			otherClusters[0], otherClusters[1] = c, otherClusters[0]
+		}
+		return c, err
+	}
+	for i, pair := range contents {
+		if i == 0 || contents[i-1].entry.clusterNumber != pair.entry.clusterNumber {
+			var err error
+			if cluster, err = z.readSourceCluster(pair.entry.clusterNumber); err != nil {
+				return err
+			}
+		}
+		var blob, err = cluster.blobAt(pair.entry.BlobNumber())
+		if err != nil {
+			return err
+		}
+		var otherCluster, otherClusterErr = otherClusterAt(pair.otherCluster)
+		if otherClusterErr != nil {
+			return otherClusterErr
+		}
+		var otherBlob, otherBlobErr = otherCluster.blobAt(pair.otherBlob)
+		if otherBlobErr != nil {
+			return otherBlobErr
+		}
+		if !bytes.Equal(blob, otherBlob) {
+			return fmt.Errorf("%w: contents of %s", ErrEntryMismatch, pair.entry.String())
+		}
+	}
+	return nil
+}
+
+// sourceCluster reads the blobs of a cluster, which is copied or compared. Clusters up to 32MB
+// are read as a whole with ClusterAt, the blobs of larger clusters are streamed one by one.
+type sourceCluster struct {
+	z          *File
+	position   uint32
+	compressed bool
+	cluster    Cluster
+	large      bool // the cluster data exceeds the 32MB of ClusterAt
+}
+
+func (z *File) readSourceCluster(clusterPosition uint32) (*sourceCluster, error) {
+	var section, clusterInformation, err = z.clusterSection(clusterPosition)
+	if err != nil {
+		return nil, err
+	}
+	var c = &sourceCluster{z: z, position: clusterPosition, compressed: clusterCompression(clusterInformation) > 1}
+	if section.Size() > maxClusterLen {
+		c.large = true
+		return c, nil
+	}
+	if c.cluster, err = z.ClusterAt(clusterPosition); err != nil {
+		return nil, err
+	}
+	// ClusterAt truncates the decompressed data at 32MB
+	if len(c.cluster.data) >= maxClusterLen {
+		c.cluster, c.large = Cluster{}, true
+	}
+	return c, nil
+}
+
+func (c *sourceCluster) blobAt(blobPosition uint32) ([]byte, error) {
+	if !c.large {
+		return c.cluster.BlobAt(blobPosition)
+	}
+	var reader, blobSize, err = c.z.BlobReaderAt(c.position, blobPosition)
+	if err != nil {
+		return nil, err
+	}
+	var blob []byte
+	if blob, err = io.ReadAll(reader); err == nil && int64(len(blob)) != blobSize {
+		err = ErrInvalidBlob
+	}
+	return blob, err
+}
@@ -0,0 +1,115 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRecompress(t *testing.T) {
+	for _, filename := range []string{filenameTestfile, "mixed_xz_zstd.zim", "new_namespace.zim", "parameters.zim",
+		"redirects.zim"} {
+		var original, err = Open(path.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer original.Close()
+
+		// a cluster size of 1 byte writes a cluster per blob, so the cluster numbers of the copy
+		// differ from the ones of the file
+		for _, options := range []WriterOptions{
+			{Compression: CompressionZstd, ClusterSize: 4096},
+			{Compression: CompressionXz, ClusterSize: 4096},
+			{Compression: CompressionZstd, ClusterSize: 1},
+		} {
+			var compression = options.Compression
+			options.UUID = original.UUID()
+			var buf bytes.Buffer
+			if err = original.Recompress(&buf, options); err != nil {
+				t.Fatalf("%s: Recompress() returned `%v`", filename, err)
+			}
+			var copied, openErr = OpenReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if openErr != nil {
+				t.Fatalf("%s: %v", filename, openErr)
+			}
+			if err = copied.ValidateChecksum(); err != nil {
+				t.Errorf("%s: %v", filename, err)
+			}
+			if err = original.CompareEntries(copied); err != nil {
+				t.Errorf("%s: CompareEntries() returned `%v`", filename, err)
+			}
+			var major, minor = original.Version()
+			if copiedMajor, copiedMinor := copied.Version(); copiedMajor != major || copiedMinor != minor ||
+				copied.UUID().String() != original.UUID().String() {
+				t.Errorf("%s: copy has version %d.%d and UUID %s", filename, copiedMajor, copiedMinor, copied.UUID())
+			}
+			for position := uint32(0); position < copied.ClusterCount(); position++ {
+				var _, information, sectionErr = copied.clusterSection(position)
+				if sectionErr != nil {
+					t.Fatal(sectionErr)
+				}
+				if c := Compression(clusterCompression(information)); c != compression && c != CompressionNone {
+					t.Errorf("%s: cluster %d has compression %d", filename, position, c)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := z.Recompress(&buf, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var copied, openErr = OpenReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if copied.UUID().String() == z.UUID().String() {
+		t.Error("z.Recompress() without UUID kept the UUID")
+	}
+
+	var other, err = Open(path.Join("testdata", "parameters.zim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err = z.CompareEntries(other); !errors.Is(err, ErrEntryMismatch) {
+		t.Errorf("comparing different files returned `%v`; want `%s`", err, ErrEntryMismatch)
+	}
+}
+
+func TestRecompressLargeClusters(t *testing.T) {
+	// blobs larger than the 32MB of ClusterAt in an uncompressed and a compressed cluster
+	var entries = []writerTestEntry{
+		writerTestEntries[0],
+		{NamespaceContent, "video.webm", "", "video/webm", strings.Repeat("\x1a\x45\xdf\xa3", 33<<18), "", false},
+		{NamespaceContent, "large.txt", "", "text/plain", strings.Repeat("large text\n", 33<<17), "", false},
+	}
+	var data = writeTestFile(t, WriterOptions{}, entries)
+	var original, err = OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries[1:] {
+		var entry, _, entryErr = original.EntryWithURL(e.namespace, []byte(e.url))
+		if entryErr != nil {
+			t.Fatal(entryErr)
+		}
+		if cluster, err := original.ClusterAt(entry.ClusterNumber()); err == nil && len(cluster.data) < maxClusterLen {
+			t.Fatalf("cluster of %s has %d bytes; want more than 32MB", e.url, len(cluster.data))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = original.Recompress(&buf, WriterOptions{Compression: CompressionXz}); err != nil {
+		t.Fatalf("Recompress() returned `%v`", err)
+	}
+	var copied, openErr = OpenReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if err = original.CompareEntries(copied); err != nil {
+		t.Errorf("CompareEntries() returned `%v`", err)
+	}
+}
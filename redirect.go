@@ -0,0 +1,60 @@
+package zim
+
+import "fmt"
+
+// DefaultRedirectDepth is the maximum number of redirects followed by
+// FollowRedirect, MainPage and LayoutPage.
+const DefaultRedirectDepth = 4
+
+// RedirectError is returned by ResolveRedirect, if a redirect chain doesn't end at a Directory Entry,
+// which is no redirect. Err is either ErrRedirectTooDeep or ErrRedirectCycle.
+type RedirectError struct {
+	Chain []uint32 // URL positions of the followed redirect targets; on a cycle followed by the repeated target
+	Err   error
+}
+
+func (e *RedirectError) Error() string {
+	var followed = len(e.Chain)
+	if e.Err == ErrRedirectCycle {
+		followed--
+	}
+	var plural = "s"
+	if followed == 1 {
+		plural = ""
+	}
+	return fmt.Sprintf("%s after %d redirect%s (URL positions %v)", e.Err, followed, plural, e.Chain)
+}
+
+// Unwrap returns ErrRedirectTooDeep or ErrRedirectCycle.
+func (e *RedirectError) Unwrap() error { return e.Err }
+
+// ResolveRedirect follows the redirects beginning at the entry until a Directory Entry is reached,
+// which is no redirect. The chain holds the URL positions of all followed redirect targets,
+// the last one is the position of the target. An entry, which is no redirect, is returned
+// unchanged with an empty chain.
+// At most maxDepth redirects are followed; maxDepth <= 0 means DefaultRedirectDepth.
+// If the chain is longer, a *RedirectError with ErrRedirectTooDeep is returned,
+// if the chain reaches a position twice, a *RedirectError with ErrRedirectCycle is returned.
+// In both cases target is the last Directory Entry reached.
+func (z *File) ResolveRedirect(entry *DirectoryEntry, maxDepth int) (target DirectoryEntry, chain []uint32, err error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultRedirectDepth
+	}
+	target = *entry
+	for target.IsRedirect() {
+		var position = target.RedirectIndex()
+		for _, visited := range chain {
+			if visited == position {
+				return target, chain, &RedirectError{Chain: append(chain, position), Err: ErrRedirectCycle}
+			}
+		}
+		if len(chain) >= maxDepth {
+			return target, chain, &RedirectError{Chain: chain, Err: ErrRedirectTooDeep}
+		}
+		chain = append(chain, position)
+		if target, err = z.EntryAtURLPosition(position); err != nil {
+			return target, chain, err
+		}
+	}
+	return target, chain, nil
+}
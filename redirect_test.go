@@ -0,0 +1,103 @@
+package zim
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+const filenameRedirects = "redirects.zim"
+
+func TestResolveRedirect(t *testing.T) {
+	var zr, openErr = Open(path.Join("testdata", filenameRedirects))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zr.Close()
+
+	var entryWithURL = func(url string) DirectoryEntry {
+		var entry, _, err = zr.EntryWithURL(NamespaceArticles, []byte(url))
+		if err != nil {
+			t.Fatalf("zr.EntryWithURL(A, %s) returned `%v`", url, err)
+		}
+		return entry
+	}
+	var positionOf = func(url string) uint32 {
+		var _, position, err = zr.EntryWithURL(NamespaceArticles, []byte(url))
+		if err != nil {
+			t.Fatalf("zr.EntryWithURL(A, %s) returned `%v`", url, err)
+		}
+		return position
+	}
+
+	// a chain of 6 redirects exceeds the default depth
+	var chain1 = entryWithURL("chain1")
+	var _, chain, err = zr.ResolveRedirect(&chain1, 0)
+	var redirectErr *RedirectError
+	if !errors.Is(err, ErrRedirectTooDeep) || !errors.As(err, &redirectErr) {
+		t.Fatalf("zr.ResolveRedirect(chain1, 0) returned `%v`; want `%s`", err, ErrRedirectTooDeep)
+	}
+	if len(chain) != DefaultRedirectDepth || len(redirectErr.Chain) != DefaultRedirectDepth {
+		t.Errorf("chain of too deep redirect has length %d; want %d", len(chain), DefaultRedirectDepth)
+	}
+	if _, err = zr.FollowRedirect(&chain1); !errors.Is(err, ErrRedirectTooDeep) {
+		t.Errorf("zr.FollowRedirect(chain1) returned `%v`; want `%s`", err, ErrRedirectTooDeep)
+	}
+	if _, err = zr.MainPage(); !errors.Is(err, ErrRedirectTooDeep) {
+		t.Errorf("zr.MainPage() returned `%v`; want `%s`", err, ErrRedirectTooDeep)
+	}
+
+	// with a larger depth the chain reaches the target
+	target, chain, err := zr.ResolveRedirect(&chain1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url := string(target.URL()); url != "target.html" {
+		t.Errorf("target of chain1 has URL `%s`; want `target.html`", url)
+	}
+	var expectedChain = []uint32{positionOf("chain2"), positionOf("chain3"), positionOf("chain4"),
+		positionOf("chain5"), positionOf("chain6"), positionOf("target.html")}
+	if len(chain) != len(expectedChain) {
+		t.Fatalf("chain of chain1 was %v; want %v", chain, expectedChain)
+	}
+	for i := range chain {
+		if chain[i] != expectedChain[i] {
+			t.Fatalf("chain of chain1 was %v; want %v", chain, expectedChain)
+		}
+	}
+
+	// the chain ending at chain5 is short enough for the default depth
+	var chain5 = entryWithURL("chain5")
+	if target, err = zr.FollowRedirect(&chain5); err != nil || string(target.URL()) != "target.html" {
+		t.Errorf("zr.FollowRedirect(chain5) returned `%s`, `%v`; want `target.html`", target.URL(), err)
+	}
+
+	// cycles are detected before the depth is exceeded
+	for _, url := range []string{"cycle1", "self"} {
+		var entry = entryWithURL(url)
+		_, chain, err = zr.ResolveRedirect(&entry, 100)
+		if !errors.Is(err, ErrRedirectCycle) {
+			t.Errorf("zr.ResolveRedirect(%s, 100) returned `%v`; want `%s`", url, err, ErrRedirectCycle)
+		}
+		if len(chain) > 3 {
+			t.Errorf("zr.ResolveRedirect(%s, 100) followed %d redirects", url, len(chain))
+		}
+		var followed = fmt.Sprintf("after %d redirect", len(chain))
+		if err == nil || !strings.Contains(err.Error(), followed) {
+			t.Errorf("error of zr.ResolveRedirect(%s, 100) was `%v`; want it to contain `%s`", url, err, followed)
+		}
+	}
+	var self = entryWithURL("self")
+	if _, _, err = zr.ResolveRedirect(&self, 100); err == nil ||
+		!strings.HasSuffix(err.Error(), fmt.Sprintf("after 1 redirect (URL positions [%d %d])", positionOf("self"), positionOf("self"))) {
+		t.Errorf("error of zr.ResolveRedirect(self, 100) was `%v`; want it to report 1 redirect", err)
+	}
+
+	// no redirect
+	var targetEntry = entryWithURL("target.html")
+	if target, chain, err = zr.ResolveRedirect(&targetEntry, 0); err != nil || len(chain) != 0 || target.url == nil {
+		t.Errorf("zr.ResolveRedirect(target.html, 0) returned chain %v, `%v`; want an empty chain", chain, err)
+	}
+}
@@ -0,0 +1,108 @@
+package zim
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// splitSuffix is the suffix of the first part of a split ZIM file.
+// The parts are named foo.zimaa, foo.zimab, ..., foo.zimaz, foo.zimba, ... foo.zimzz.
+const splitSuffix = "aa"
+
+// splitFile reads the parts of a split ZIM file as one contiguous file.
+type splitFile struct {
+	parts   []*os.File
+	offsets []int64 // file position of each part followed by the total size
+}
+
+// splitBasename returns the name of a split ZIM file without the suffix of the parts,
+// if filename is its first part or filename doesn't exist, but its first part.
+func splitBasename(filename string) (basename string, split bool) {
+	if strings.HasSuffix(filename, ".zim"+splitSuffix) {
+		return strings.TrimSuffix(filename, splitSuffix), true
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		return "", false
+	}
+	if _, err := os.Stat(filename + splitSuffix); err == nil {
+		return filename, true
+	}
+	return "", false
+}
+
+// splitPartName returns the name of the part at the index; the first part has index 0.
+func splitPartName(basename string, index int) string {
+	return basename + string([]byte{byte('a' + index/26), byte('a' + index%26)})
+}
+
+// openSplitFile opens all parts of a split ZIM file.
+func openSplitFile(basename string) (*splitFile, error) {
+	const maxParts = 26 * 26
+	var result = &splitFile{offsets: []int64{0}}
+	for index := 0; index < maxParts; index++ {
+		var part, err = os.Open(splitPartName(basename, index))
+		if err != nil {
+			if index > 0 && os.IsNotExist(err) {
+				break
+			}
+			result.Close()
+			return nil, err
+		}
+		result.parts = append(result.parts, part)
+		var info, statErr = part.Stat()
+		if statErr != nil {
+			result.Close()
+			return nil, statErr
+		}
+		result.offsets = append(result.offsets, result.Size()+info.Size())
+	}
+	return result, nil
+}
+
+// Size returns the total size of all parts.
+func (s *splitFile) Size() int64 {
+	return s.offsets[len(s.offsets)-1]
+}
+
+// ReadAt reads from the parts containing the range, so reads can span the boundaries of parts.
+func (s *splitFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("zim: negative offset")
+	}
+	var n int
+	for n < len(p) {
+		var position = off + int64(n)
+		if position >= s.Size() {
+			return n, io.EOF
+		}
+		var index = sort.Search(len(s.parts), func(i int) bool { return s.offsets[i+1] > position })
+		var end = len(p)
+		if remaining := s.offsets[index+1] - position; remaining < int64(end-n) {
+			end = n + int(remaining)
+		}
+		var m, err = s.parts[index].ReadAt(p[n:end], position-s.offsets[index])
+		n += m
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		if m == 0 {
+			// the part is shorter than when it was opened
+			return n, io.ErrUnexpectedEOF
+		}
+	}
+	return n, nil
+}
+
+// Close closes all parts.
+func (s *splitFile) Close() error {
+	var err error
+	for _, part := range s.parts {
+		if closeErr := part.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
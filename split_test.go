@@ -0,0 +1,89 @@
+package zim
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// splitTestfile splits a test file into parts of the given size
+// in a temporary directory and returns the name without the suffix of the parts.
+func splitTestfile(t *testing.T, filename string, partSize int) string {
+	var data, readErr = ioutil.ReadFile(path.Join("testdata", filename))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	var basename = path.Join(t.TempDir(), filename)
+	for index := 0; len(data) > 0; index++ {
+		var n = partSize
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := ioutil.WriteFile(splitPartName(basename, index), data[:n], 0644); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	return basename
+}
+
+func TestOpenSplit(t *testing.T) {
+	var tests = []struct {
+		filename string
+		partSize int
+	}{
+		// small parts, so that clusters and Directory Entries span the boundaries of parts
+		{filenameMixedCompression, 300},
+		{filenameTestfile, 100000},
+	}
+	for _, test := range tests {
+		var zf, openErr = Open(path.Join("testdata", test.filename))
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		defer zf.Close()
+		var info, _ = os.Stat(path.Join("testdata", test.filename))
+
+		var basename = splitTestfile(t, test.filename, test.partSize)
+		for _, name := range []string{basename, basename + "aa"} {
+			var zs, splitErr = Open(name)
+			if splitErr != nil {
+				t.Errorf("Open(%s): %s", name, splitErr)
+				continue
+			}
+			if zs.Filesize() != int(info.Size()) || zs.size != info.Size() {
+				t.Errorf("%s: zs.Filesize() returned %d; want %d", name, zs.Filesize(), info.Size())
+			}
+			if err := zs.ValidateChecksum(); err != nil {
+				t.Errorf("%s: %s", name, err)
+			}
+			for position := uint32(0); position < zf.ClusterCount(); position++ {
+				var expected, _ = zf.ClusterAt(position)
+				var cluster, clusterErr = zs.ClusterAt(position)
+				if clusterErr != nil || !bytes.Equal(cluster.data, expected.data) {
+					t.Errorf("%s: zs.ClusterAt(%d) returned different data, %v", name, position, clusterErr)
+				}
+			}
+			for position := uint32(0); position < zf.ArticleCount(); position++ {
+				var expected, _ = zf.EntryAtURLPosition(position)
+				var entry, entryErr = zs.EntryAtURLPosition(position)
+				if entryErr != nil || entry.String() != expected.String() {
+					t.Errorf("%s: zs.EntryAtURLPosition(%d) returned %s, %v; want %s",
+						name, position, entry.String(), entryErr, expected.String())
+				}
+			}
+			zs.Close()
+		}
+		if zs, err := OpenMmap(basename); err != nil {
+			t.Errorf("OpenMmap(%s): %s", basename, err)
+		} else {
+			zs.Close()
+		}
+	}
+
+	if _, err := Open(path.Join(t.TempDir(), "missing.zim")); !os.IsNotExist(err) {
+		t.Errorf("Open() of a missing file returned `%v`; want a not exist error", err)
+	}
+}
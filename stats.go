@@ -0,0 +1,125 @@
+package zim
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// NamespaceRange returns the first and the last position in the URL pointerlist of the
+// Directory Entries in the namespace, which are found by binary search.
+// The namespace has last-first+1 Directory Entries.
+// If the namespace is empty, the returned error is ErrNotFound.
+func (z *File) NamespaceRange(namespace Namespace) (first, last uint32, err error) {
+	if first, err = z.urlLowerBound(namespace, nil); err != nil {
+		return
+	}
+	var end = z.header.articleCount
+	if namespace < Namespace(0xFF) {
+		if end, err = z.urlLowerBound(namespace+1, nil); err != nil {
+			return
+		}
+	}
+	if end <= first {
+		return first, first, ErrNotFound
+	}
+	return first, end - 1, nil
+}
+
+// Stats are the statistics of the Directory Entries and clusters of a ZIM file.
+type Stats struct {
+	Entries          uint32               // number of Directory Entries
+	Namespaces       map[Namespace]uint32 // Directory Entries per namespace
+	Mimetypes        map[string]uint32    // Directory Entries with contents per Mimetype
+	Redirects        uint32
+	LinkTargets      uint32
+	DeletedEntries   uint32
+	InvalidMimetypes uint32 // Directory Entries with a Mimetype missing in the Mimetype list
+	Clusters         uint32
+	Blobs            uint64 // number of blobs in all clusters
+	BlobBytes        uint64 // total size of all blobs, which is the size of the uncompressed contents
+}
+
+// Stats walks once over all Directory Entries and reads the blob positions of all clusters,
+// which only needs to decompress the beginning of compressed clusters.
+// Blobs shared by several Directory Entries are counted once.
+func (z *File) Stats() (Stats, error) {
+	var stats = Stats{
+		Namespaces: make(map[Namespace]uint32),
+		Mimetypes:  make(map[string]uint32),
+		Clusters:   z.header.clusterCount,
+	}
+	for entry, err := range z.Entries(0) {
+		if err != nil {
+			return stats, err
+		}
+		stats.Entries++
+		stats.Namespaces[entry.namespace]++
+		switch {
+		case entry.IsRedirect():
+			stats.Redirects++
+		case entry.IsLinkTarget():
+			stats.LinkTargets++
+		case entry.IsDeletedEntry():
+			stats.DeletedEntries++
+		default:
+			if mimetype, err := z.MimetypeOf(&entry); err == nil {
+				stats.Mimetypes[mimetype]++
+			} else {
+				stats.InvalidMimetypes++
+			}
+		}
+	}
+	for position := uint32(0); position < z.header.clusterCount; position++ {
+		var blobs, blobBytes, err = z.clusterBlobBytes(position)
+		if err != nil {
+			return stats, err
+		}
+		stats.Blobs += blobs
+		stats.BlobBytes += blobBytes
+	}
+	return stats, nil
+}
+
+// clusterBlobBytes returns the number of blobs of the cluster and their total size,
+// which are calculated from the first and the last blob position.
+func (z *File) clusterBlobBytes(clusterPosition uint32) (blobs, blobBytes uint64, err error) {
+	var section, clusterInformation, sectionErr = z.clusterSection(clusterPosition)
+	if sectionErr != nil {
+		return 0, 0, sectionErr
+	}
+	var clusterReader, clusterReaderErr = decompressor(section, clusterInformation)
+	if clusterReaderErr != nil {
+		return 0, 0, clusterReaderErr
+	}
+	var offsetSize = uint64(clusterOffsetSize(clusterInformation))
+	var readOffset = func() (uint64, error) {
+		if offsetSize == extendedOffsetSize {
+			return readUint64(clusterReader)
+		}
+		var offset, err = readUint32(clusterReader)
+		return uint64(offset), err
+	}
+	var first, last uint64
+	if first, err = readOffset(); err != nil {
+		return 0, 0, clusterError(section, blobError(err))
+	}
+	if first < offsetSize || first%offsetSize != 0 {
+		return 0, 0, clusterError(section, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob))
+	}
+	blobs = first/offsetSize - 1
+	if blobs == 0 {
+		return 0, 0, nil
+	}
+	// skip to the last blob position
+	if _, err = io.CopyN(ioutil.Discard, clusterReader, int64((blobs-1)*offsetSize)); err == nil {
+		last, err = readOffset()
+	}
+	if err != nil {
+		return 0, 0, clusterError(section, blobError(err))
+	}
+	if last < first {
+		return 0, 0, clusterError(section, fmt.Errorf("%w: invalid blob index", ErrInvalidBlob))
+	}
+	return blobs, last - first, nil
+}
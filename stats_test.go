@@ -0,0 +1,76 @@
+package zim
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestNamespaceRangeAndStats(t *testing.T) {
+	var zm, openErr = Open(path.Join("testdata", filenameMixedCompression))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer zm.Close()
+
+	var ranges = []struct {
+		namespace   Namespace
+		first, last uint32
+	}{
+		{NamespaceLayout, 0, 0},
+		{NamespaceArticles, 1, 4},
+		{NamespaceZimMetadata, 5, 6},
+	}
+	for _, test := range ranges {
+		var first, last, err = zm.NamespaceRange(test.namespace)
+		if err != nil || first != test.first || last != test.last {
+			t.Errorf("zm.NamespaceRange(%c) returned %d, %d, `%v`; want %d, %d",
+				test.namespace, first, last, err, test.first, test.last)
+		}
+	}
+	for _, namespace := range []Namespace{NamespaceImagesFiles, Namespace(0), Namespace(0xFF)} {
+		if _, _, err := zm.NamespaceRange(namespace); !errors.Is(err, ErrNotFound) {
+			t.Errorf("zm.NamespaceRange(%d) returned `%v`; want `%s`", namespace, err, ErrNotFound)
+		}
+	}
+
+	var stats, statsErr = zm.Stats()
+	if statsErr != nil {
+		t.Fatal(statsErr)
+	}
+	var blobBytes uint64
+	for entry, err := range zm.Entries(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !entry.IsRedirect() {
+			var _, size, sizeErr = zm.BlobReader(&entry)
+			if sizeErr != nil {
+				t.Fatal(sizeErr)
+			}
+			blobBytes += uint64(size)
+		}
+	}
+	if stats.Entries != 7 || stats.Redirects != 1 || stats.LinkTargets != 0 || stats.DeletedEntries != 0 ||
+		stats.InvalidMimetypes != 0 || stats.Clusters != 3 || stats.Blobs != 6 || stats.BlobBytes != blobBytes {
+		t.Errorf("zm.Stats() returned %+v; want 7 entries, 1 redirect, 3 clusters, 6 blobs with %d bytes", stats, blobBytes)
+	}
+	var expectedNamespaces = map[Namespace]uint32{NamespaceLayout: 1, NamespaceArticles: 4, NamespaceZimMetadata: 2}
+	if len(stats.Namespaces) != len(expectedNamespaces) {
+		t.Errorf("stats.Namespaces was %v; want %v", stats.Namespaces, expectedNamespaces)
+	}
+	for namespace, count := range expectedNamespaces {
+		if stats.Namespaces[namespace] != count {
+			t.Errorf("stats.Namespaces was %v; want %v", stats.Namespaces, expectedNamespaces)
+		}
+	}
+	var expectedMimetypes = map[string]uint32{"text/html": 2, "text/plain": 3, "text/css": 1}
+	if len(stats.Mimetypes) != len(expectedMimetypes) {
+		t.Errorf("stats.Mimetypes was %v; want %v", stats.Mimetypes, expectedMimetypes)
+	}
+	for mimetype, count := range expectedMimetypes {
+		if stats.Mimetypes[mimetype] != count {
+			t.Errorf("stats.Mimetypes was %v; want %v", stats.Mimetypes, expectedMimetypes)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Subset writes a new ZIM file to w with the Directory Entries, for which selected returns true,
+// and the Directory Entries they need:
+//   - the targets of selected redirects,
+//   - the images, stylesheets and scripts referenced by the <img>, <link> and <script> elements
+//     of selected HTML pages, even if they are not selected themselves,
+//   - all redirects pointing to a Directory Entry of the subset,
+//   - the metadata and the other contents of NamespaceWellKnown.
+//
+// Directory Entries of NamespaceIndexes, like full-text indexes, describe the complete file and
+// are never copied; the metadata Counter and the front article listing are written anew.
+// The main page and the layout page are kept, if they are in the subset.
+// The version of the file is kept, so options.MajorVersion and options.MinorVersion are ignored.
+func (z *File) Subset(w io.Writer, selected func(e *DirectoryEntry) bool, options WriterOptions) error {
+	options.MajorVersion, options.MinorVersion = z.Version()
+	var keep, frontArticles, err = z.subsetEntries(selected)
+	if err != nil {
+		return err
+	}
+	var zw *Writer
+	if zw, err = NewWriter(w, options); err != nil {
+		return err
+	}
+	if err = z.copyEntries(zw, keep, frontArticles); err != nil {
+		zw.Abort()
+		return err
+	}
+	return zw.Close()
+}
+
+// noRedirect marks URL positions of Directory Entries, which are no redirects.
+const noRedirect = ^uint32(0)
+
+// subsetEntries returns the URL positions of the Directory Entries in the subset
+// and of the front articles.
+func (z *File) subsetEntries(selected func(e *DirectoryEntry) bool) (keep, frontArticles []bool, err error) {
+	var count = z.header.articleCount
+	keep = make([]bool, count)
+	var namespaces = make([]Namespace, count)
+	var targets = make([]uint32, count)
+	var pages []uint32 // selected Directory Entries, whose references are followed
+
+	var _, _, listingErr = z.EntryWithURL(NamespaceIndexes, []byte(frontArticleListingURL))
+	var searchFrontArticles = z.HasNewNamespaceScheme() && errors.Is(listingErr, ErrNotFound)
+	if z.HasNewNamespaceScheme() {
+		frontArticles = make([]bool, count)
+	}
+
+	var position uint32
+	for entry, entryErr := range z.Entries(0) {
+		if entryErr != nil {
+			return nil, nil, entryErr
+		}
+		namespaces[position] = entry.namespace
+		targets[position] = noRedirect
+		if entry.IsRedirect() {
+			targets[position] = entry.RedirectIndex()
+		}
+		switch {
+		case entry.namespace == NamespaceIndexes:
+		case entry.namespace == NamespaceZimMetadata:
+			keep[position] = string(entry.url) != "Counter"
+		case entry.namespace == NamespaceWellKnown && !entry.IsRedirect():
+			keep[position] = true
+		case selected(&entry):
+			pages = append(pages, position)
+		}
+		if searchFrontArticles {
+			frontArticles[position] = z.isFrontArticle(&entry)
+		}
+		position++
+	}
+	if frontArticles != nil && !searchFrontArticles {
+		if err = z.readFrontArticleListing(frontArticles); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// mark keeps the Directory Entry and the targets of redirects;
+	// it returns false, if the Directory Entry can't be kept
+	var mark func(position uint32, depth int) bool
+	mark = func(position uint32, depth int) bool {
+		switch {
+		case keep[position]:
+			return true
+		case namespaces[position] == NamespaceIndexes || depth > DefaultRedirectDepth:
+			return false
+		}
+		keep[position] = true
+		if targets[position] != noRedirect && !mark(targets[position], depth+1) {
+			keep[position] = false
+			return false
+		}
+		return true
+	}
+	var selectedPages []uint32
+	for _, position := range pages {
+		if mark(position, 0) {
+			selectedPages = append(selectedPages, position)
+		}
+	}
+	if err = z.markReferences(selectedPages, targets, func(position uint32) { mark(position, 0) }); err != nil {
+		return nil, nil, err
+	}
+
+	// redirects pointing into the subset
+	for position := range keep {
+		var target = uint32(position)
+		for depth := 0; depth <= DefaultRedirectDepth && !keep[target] && targets[target] != noRedirect; depth++ {
+			target = targets[target]
+		}
+		if keep[target] && namespaces[position] != NamespaceIndexes {
+			keep[position] = true
+		}
+	}
+	return keep, frontArticles, nil
+}
+
+// readFrontArticleListing sets the URL positions of the front article listing to true.
+func (z *File) readFrontArticleListing(frontArticles []bool) error {
+	var count, err = z.FrontArticleCount()
+	if err != nil || z.frontArticles.listing == nil {
+		return err
+	}
+	for position := uint32(0); position < count; position++ {
+		var urlPosition uint32
+		if urlPosition, err = readUint32At(z.frontArticles.listing, int64(position)*4); err != nil {
+			return readError("read front article listing", int64(position)*4, err, ErrInvalidListing)
+		}
+		if urlPosition < uint32(len(frontArticles)) {
+			frontArticles[urlPosition] = true
+		}
+	}
+	return nil
+}
+
+// markReferences calls mark with the URL positions of the Directory Entries referenced by
+// the HTML pages at the URL positions. Redirects are followed to the HTML page first.
+// The pages are read in cluster order.
+func (z *File) markReferences(positions []uint32, targets []uint32, mark func(position uint32)) error {
+	var pages []DirectoryEntry
+	for _, position := range positions {
+		for depth := 0; depth < DefaultRedirectDepth && targets[position] != noRedirect; depth++ {
+			position = targets[position]
+		}
+		var entry, err = z.EntryAtURLPosition(position)
+		if err != nil {
+			return err
+		}
+		if mimetype, _ := z.MimetypeOf(&entry); strings.HasPrefix(mimetype, "text/html") {
+			pages = append(pages, entry)
+		}
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		if pages[i].clusterNumber != pages[j].clusterNumber {
+			return pages[i].clusterNumber < pages[j].clusterNumber
+		}
+		return pages[i].BlobNumber() < pages[j].BlobNumber()
+	})
+
+	var cluster *sourceCluster
+	for i, page := range pages {
+		if i == 0 || pages[i-1].clusterNumber != page.clusterNumber {
+			var err error
+			if cluster, err = z.readSourceCluster(page.clusterNumber); err != nil {
+				return err
+			}
+		}
+		var data, err = cluster.blobAt(page.BlobNumber())
+		if err != nil {
+			return err
+		}
+		for _, reference := range htmlReferences(data) {
+			var namespace, referenceURL, ok = resolveReference(page.namespace, string(page.url), reference)
+			if !ok {
+				continue
+			}
+			var _, position, searchErr = z.EntryWithURL(namespace, []byte(referenceURL))
+			if errors.Is(searchErr, ErrNotFound) {
+				continue
+			}
+			if searchErr != nil {
+				return searchErr
+			}
+			mark(position)
+		}
+	}
+	return nil
+}
+
+// htmlReferences returns the URLs of the images, stylesheets, icons and scripts of the HTML page.
+func htmlReferences(data []byte) []string {
+	var references []string
+	var tokenizer = html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return references
+		case html.StartTagToken, html.SelfClosingTagToken:
+			var token = tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Img:
+				references = appendAttribute(references, token, "src")
+				for _, candidate := range strings.Split(attributeValue(token, "srcset"), ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						references = append(references, fields[0])
+					}
+				}
+			case atom.Script:
+				references = appendAttribute(references, token, "src")
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attributeValue(token, "rel"))) {
+					if rel == "stylesheet" || rel == "icon" || rel == "preload" || rel == "modulepreload" {
+						references = appendAttribute(references, token, "href")
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func attributeValue(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func appendAttribute(references []string, token html.Token, key string) []string {
+	if value := strings.TrimSpace(attributeValue(token, key)); len(value) > 0 {
+		return append(references, value)
+	}
+	return references
+}
+
+// resolveReference resolves the relative URL of a reference in the page with the namespace
+// and URL to the namespace and URL of the referenced Directory Entry, like a browser does
+// for a page served at "{namespace}/{url}". It returns false for external and absolute URLs.
+func resolveReference(namespace Namespace, pageURL, reference string) (Namespace, string, bool) {
+	var u, err = url.Parse(reference)
+	if err != nil || len(u.Scheme) > 0 || len(u.Host) > 0 || len(u.Path) == 0 || strings.HasPrefix(u.Path, "/") {
+		return 0, "", false
+	}
+	var resolved = path.Join(path.Dir(string(namespace)+"/"+pageURL), u.Path)
+	if len(resolved) < 3 || resolved[1] != '/' {
+		return 0, "", false
+	}
+	return Namespace(resolved[0]), resolved[2:], true
+}
@@ -0,0 +1,181 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"testing"
+)
+
+func subsetOf(t *testing.T, zf *File, clusterSize int, selected func(e *DirectoryEntry) bool) *File {
+	var buf bytes.Buffer
+	if err := zf.Subset(&buf, selected, WriterOptions{ClusterSize: clusterSize}); err != nil {
+		t.Fatal(err)
+	}
+	var subset, err = OpenReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = subset.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+	return subset
+}
+
+func entryPaths(t *testing.T, zf *File, namespace Namespace) []string {
+	var paths []string
+	for entry, err := range zf.EntriesInNamespace(namespace, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, string(entry.URL()))
+	}
+	return paths
+}
+
+func TestSubset(t *testing.T) {
+	var original, err = Open(path.Join("testdata", "new_namespace.zim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer original.Close()
+
+	var subset = subsetOf(t, original, 4096, func(e *DirectoryEntry) bool { return string(e.URL()) == "index.html" })
+	if paths := entryPaths(t, subset, NamespaceContent); strings.Join(paths, ",") != "assets/style.css,index.html" {
+		t.Errorf("subset has the contents %v; want [assets/style.css index.html]", paths)
+	}
+	if mainPage, err := subset.MainPage(); err != nil || string(mainPage.URL()) != "index.html" {
+		t.Errorf("subset.MainPage() returned %s, `%v`", mainPage.String(), err)
+	}
+	if titles := frontArticleTitles(t, subset); strings.Join(titles, ",") != "Welcome" {
+		t.Errorf("front articles of the subset were %v; want [Welcome]", titles)
+	}
+	if illustration, err := subset.Illustration(48, 1); err != nil || len(illustration) == 0 ||
+		subset.Title() != "New namespace test" {
+		t.Errorf("subset lost the metadata: `%v`", err)
+	}
+	if counter := subset.Counter(); len(counter) != 2 || counter["text/html"] != 1 || counter["text/css"] != 1 {
+		t.Errorf("subset.Counter() was %v", counter)
+	}
+
+	// a selected redirect pulls in its target, but not the main page
+	subset = subsetOf(t, original, 4096, func(e *DirectoryEntry) bool { return string(e.URL()) == "Bar" })
+	if paths := entryPaths(t, subset, NamespaceContent); strings.Join(paths, ",") != "Bar,Foo" {
+		t.Errorf("subset has the contents %v; want [Bar Foo]", paths)
+	}
+	if _, err := subset.MainPage(); !errors.Is(err, ErrNoMainPage) {
+		t.Errorf("subset.MainPage() returned `%v`; want `%s`", err, ErrNoMainPage)
+	}
+	if paths := entryPaths(t, subset, NamespaceWellKnown); len(paths) != 0 {
+		t.Errorf("subset has the well-known entries %v", paths)
+	}
+}
+
+func TestSubsetReferences(t *testing.T) {
+	var mainPage, mainPageErr = z.MainPage()
+	if mainPageErr != nil {
+		t.Fatal(mainPageErr)
+	}
+	// a cluster per blob, so the cluster numbers of the subset differ from the ones of the file
+	var subset = subsetOf(t, z, 1, func(e *DirectoryEntry) bool {
+		return e.Namespace() == mainPage.Namespace() && bytes.Equal(e.URL(), mainPage.URL())
+	})
+	if subset.ArticleCount() >= z.ArticleCount() {
+		t.Errorf("subset has %d of %d Directory Entries", subset.ArticleCount(), z.ArticleCount())
+	}
+	if subsetMainPage, err := subset.MainPage(); err != nil || !bytes.Equal(subsetMainPage.URL(), mainPage.URL()) {
+		t.Errorf("subset.MainPage() returned %s, `%v`", subsetMainPage.String(), err)
+	}
+
+	// every resource of the main page is copied
+	var reader, _, readerErr = z.BlobReader(&mainPage)
+	if readerErr != nil {
+		t.Fatal(readerErr)
+	}
+	var page, readErr = io.ReadAll(reader)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	var resources int
+	for _, reference := range htmlReferences(page) {
+		var namespace, url, ok = resolveReference(mainPage.Namespace(), string(mainPage.URL()), reference)
+		if !ok {
+			continue
+		}
+		var entry, _, err = z.EntryWithURL(namespace, []byte(url))
+		if err != nil {
+			continue
+		}
+		resources++
+		var copied, _, copiedErr = subset.EntryWithURL(namespace, []byte(url))
+		if copiedErr != nil {
+			t.Errorf("resource %c/%s of the main page is missing: %v", namespace, url, copiedErr)
+			continue
+		}
+		if !entry.IsRedirect() && !bytes.Equal(readBlob(t, z, &entry), readBlob(t, subset, &copied)) {
+			t.Errorf("contents of %c/%s differ", namespace, url)
+		}
+	}
+	if resources == 0 {
+		t.Fatal("main page has no resources")
+	}
+
+	// only the main page and redirects to it are articles
+	var articles []string
+	for entry, err := range subset.EntriesInNamespace(NamespaceArticles, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.IsRedirect() {
+			if target, err := subset.FollowRedirect(&entry); err != nil || !bytes.Equal(target.URL(), mainPage.URL()) {
+				t.Errorf("redirect %s points to %s, `%v`", entry.String(), target.String(), err)
+			}
+			continue
+		}
+		articles = append(articles, string(entry.URL()))
+	}
+	if len(articles) != 1 {
+		t.Errorf("subset has the articles %v; want only the main page", articles)
+	}
+}
+
+func readBlob(t *testing.T, zf *File, e *DirectoryEntry) []byte {
+	var reader, _, err = zf.BlobReader(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data, readErr = io.ReadAll(reader)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return data
+}
+
+func TestResolveReference(t *testing.T) {
+	for _, test := range []struct {
+		namespace Namespace
+		page      string
+		reference string
+		want      string
+	}{
+		{NamespaceArticles, "Paris.html", "../I/m/Paris.jpg", "I/m/Paris.jpg"},
+		{NamespaceArticles, "Paris.html", "../-/s/style.css?v=1#top", "-/s/style.css"},
+		{NamespaceContent, "docs/a.html", "../assets/a%20b.png", "C/assets/a b.png"},
+		{NamespaceContent, "index.html", "assets/style.css", "C/assets/style.css"},
+		{NamespaceContent, "index.html", "https://example.com/a.png", ""},
+		{NamespaceContent, "index.html", "/assets/style.css", ""},
+		{NamespaceContent, "index.html", "#top", ""},
+		{NamespaceContent, "index.html", "../..", ""},
+	} {
+		var namespace, url, ok = resolveReference(test.namespace, test.page, test.reference)
+		var got string
+		if ok {
+			got = string(namespace) + "/" + url
+		}
+		if got != test.want {
+			t.Errorf("resolveReference(%c, %s, %s) returned `%s`; want `%s`", test.namespace, test.page, test.reference, got, test.want)
+		}
+	}
+}
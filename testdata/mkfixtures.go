@@ -0,0 +1,382 @@
+//go:build ignore
+
+// mkfixtures writes the small synthetic ZIM files used by the tests.
+// Run it from the repository root with `go run testdata/mkfixtures.go`.
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	compressionNone = 1
+	compressionXz   = 4
+	compressionZstd = 5
+)
+
+type fixtureEntry struct {
+	namespace  byte
+	url        string
+	title      string
+	mimetype   string
+	cluster    int
+	content    string
+	redirectTo string // "<namespace>/<url>" of the redirect target
+	parameter  string // extra parameter data, at most 255 bytes
+}
+
+type fixture struct {
+	filename     string
+	majorVersion uint16
+	minorVersion uint16
+	uuid         [16]byte
+	mainPage     string // "<namespace>/<url>" or empty
+	clusters     []uint8
+	entries      []fixtureEntry
+	// frontArticles are the paths of X/listing/titleOrdered/v1 in title order,
+	// which is stored in the first cluster
+	frontArticles []string
+}
+
+func (e *fixtureEntry) path() string { return string(e.namespace) + "/" + e.url }
+
+func (e *fixtureEntry) sortTitle() string {
+	if len(e.title) > 0 {
+		return e.title
+	}
+	return e.url
+}
+
+func repeat(s string, n int) string { return strings.Repeat(s, n) }
+
+// binaryData returns n bytes of every value, as found in images.
+func binaryData(n int) string {
+	var data = make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return string(data)
+}
+
+var fixtures = []fixture{
+	{
+		filename:     "mixed_xz_zstd.zim",
+		majorVersion: 5,
+		uuid:         [16]byte{0x6d, 0x69, 0x78, 0x65, 0x64, 0x2d, 0x78, 0x7a, 0x2d, 0x7a, 0x73, 0x74, 0x64, 0x00, 0x00, 0x01},
+		mainPage:     "A/index.html",
+		clusters:     []uint8{compressionNone, compressionXz, compressionZstd},
+		entries: []fixtureEntry{
+			{namespace: 'M', url: "Title", mimetype: "text/plain", cluster: 0, content: "Mixed compression test"},
+			{namespace: 'M', url: "Language", mimetype: "text/plain", cluster: 0, content: "eng"},
+			{namespace: 'A', url: "index.html", title: "Index", mimetype: "text/html", cluster: 1,
+				content: "<html><head><title>Index</title></head><body>" + repeat("<p>xz compressed paragraph</p>", 40) + "</body></html>"},
+			{namespace: '-', url: "style.css", mimetype: "text/css", cluster: 1,
+				content: repeat("p { margin: 0; }\n", 30)},
+			{namespace: 'A', url: "zstd.html", title: "Zstandard", mimetype: "text/html", cluster: 2,
+				content: "<html><head><title>Zstandard</title></head><body>" + repeat("<p>zstd compressed paragraph</p>", 40) + "</body></html>"},
+			{namespace: 'A', url: "notes.txt", title: "Notes", mimetype: "text/plain", cluster: 2,
+				content: repeat("zstd plain text line\n", 25)},
+			{namespace: 'A', url: "Redirect.html", title: "Redirect", mimetype: "", redirectTo: "A/zstd.html"},
+		},
+	},
+	{
+		// ZIM 6.1 with the new namespace scheme and without a main page in the header
+		filename:     "new_namespace.zim",
+		majorVersion: 6,
+		minorVersion: 1,
+		uuid:         [16]byte{0x6e, 0x65, 0x77, 0x2d, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x00, 0x00, 0x01},
+		clusters:     []uint8{compressionNone, compressionZstd},
+		entries: []fixtureEntry{
+			{namespace: 'M', url: "Title", mimetype: "text/plain", cluster: 0, content: "New namespace test"},
+			{namespace: 'M', url: "Language", mimetype: "text/plain", cluster: 0, content: "eng"},
+			{namespace: 'M', url: "Illustration_48x48@1", mimetype: "image/png", cluster: 0,
+				content: "\x89PNG\r\n\x1a\n" + repeat("\x00", 40)},
+			{namespace: 'C', url: "index.html", title: "Welcome", mimetype: "text/html", cluster: 1,
+				content: "<html><head><title>Welcome</title><link rel=\"stylesheet\" href=\"assets/style.css\"></head><body>" +
+					repeat("<p><a href=\"Foo\">Foo</a></p>", 20) + "</body></html>"},
+			{namespace: 'C', url: "Foo", title: "Foo", mimetype: "text/html", cluster: 1,
+				content: "<html><head><title>Foo</title></head><body>" + repeat("<p>new namespace paragraph</p>", 30) + "</body></html>"},
+			{namespace: 'C', url: "Food", title: "Food", mimetype: "text/html", cluster: 1,
+				content: "<html><head><title>Food</title></head><body>" + repeat("<p>food paragraph</p>", 30) + "</body></html>"},
+			{namespace: 'C', url: "assets/style.css", mimetype: "text/css", cluster: 0,
+				content: repeat("p { margin: 0; }\n", 10)},
+			{namespace: 'C', url: "Bar", title: "Bar", redirectTo: "C/Foo"},
+			{namespace: 'W', url: "mainPage", redirectTo: "C/index.html"},
+		},
+		frontArticles: []string{"C/Foo", "C/Food", "C/index.html"},
+	},
+	{
+		// Directory Entries with extra parameter data
+		filename:     "parameters.zim",
+		majorVersion: 5,
+		uuid:         [16]byte{0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		mainPage:     "A/index.html",
+		clusters:     []uint8{compressionNone},
+		entries: []fixtureEntry{
+			{namespace: 'M', url: "Title", mimetype: "text/plain", cluster: 0, content: "Parameter test"},
+			{namespace: 'A', url: "index.html", title: "Index", mimetype: "text/html", cluster: 0,
+				content: "<html><body>index</body></html>", parameter: "\x00\x01\x02\xff"},
+			{namespace: 'A', url: "long.html", title: "Long", mimetype: "text/html", cluster: 0,
+				content: "<html><body>long</body></html>", parameter: repeat("0123456789abcdef", 15) + "0123456789abcde"},
+			{namespace: 'A', url: "plain.html", title: "Plain", mimetype: "text/html", cluster: 0,
+				content: "<html><body>plain</body></html>"},
+			{namespace: 'A', url: "Redirect.html", title: "Redirect", redirectTo: "A/index.html", parameter: "r"},
+		},
+	},
+	{
+		// redirect chains and cycles
+		filename:     "redirects.zim",
+		majorVersion: 5,
+		uuid:         [16]byte{0x72, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x73, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		mainPage:     "A/chain1",
+		clusters:     []uint8{compressionNone},
+		entries: []fixtureEntry{
+			{namespace: 'M', url: "Title", mimetype: "text/plain", cluster: 0, content: "Redirect test"},
+			{namespace: 'A', url: "target.html", title: "Target", mimetype: "text/html", cluster: 0,
+				content: "<html><body>target</body></html>"},
+			{namespace: 'A', url: "chain1", redirectTo: "A/chain2"},
+			{namespace: 'A', url: "chain2", redirectTo: "A/chain3"},
+			{namespace: 'A', url: "chain3", redirectTo: "A/chain4"},
+			{namespace: 'A', url: "chain4", redirectTo: "A/chain5"},
+			{namespace: 'A', url: "chain5", redirectTo: "A/chain6"},
+			{namespace: 'A', url: "chain6", redirectTo: "A/target.html"},
+			{namespace: 'A', url: "cycle1", redirectTo: "A/cycle2"},
+			{namespace: 'A', url: "cycle2", redirectTo: "A/cycle3"},
+			{namespace: 'A', url: "cycle3", redirectTo: "A/cycle1"},
+			{namespace: 'A', url: "self", redirectTo: "A/self"},
+		},
+	},
+	{
+		// metadata values of any size, also binary ones
+		filename:     "metadata.zim",
+		majorVersion: 6,
+		minorVersion: 1,
+		uuid:         [16]byte{0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		mainPage:     "C/index.html",
+		clusters:     []uint8{compressionNone, compressionZstd},
+		entries: []fixtureEntry{
+			{namespace: 'M', url: "Name", mimetype: "text/plain", cluster: 0, content: "metadata_test_all"},
+			{namespace: 'M', url: "Title", mimetype: "text/plain", cluster: 0, content: "Metadata test"},
+			{namespace: 'M', url: "Creator", mimetype: "text/plain", cluster: 0, content: "go-zim"},
+			{namespace: 'M', url: "Publisher", mimetype: "text/plain", cluster: 0, content: "go-zim"},
+			{namespace: 'M', url: "Date", mimetype: "text/plain", cluster: 0, content: "2024-01-31"},
+			{namespace: 'M', url: "Description", mimetype: "text/plain", cluster: 0, content: "Metadata of any size"},
+			{namespace: 'M', url: "LongDescription", mimetype: "text/plain", cluster: 0,
+				content: repeat("A long description of the metadata test file. ", 64)},
+			{namespace: 'M', url: "Language", mimetype: "text/plain", cluster: 0, content: "eng,deu"},
+			{namespace: 'M', url: "Tags", mimetype: "text/plain", cluster: 0,
+				content: "wikipedia;_category:wikipedia;_pictures:no;_videos:no;_details:yes;_ftindex:yes"},
+			{namespace: 'M', url: "Counter", mimetype: "text/plain", cluster: 0, content: "text/html=1"},
+			{namespace: 'M', url: "Illustration_48x48@1", mimetype: "image/png", cluster: 0,
+				content: "\x89PNG\r\n\x1a\n" + binaryData(600)},
+			{namespace: 'M', url: "Illustration_96x96@2", mimetype: "image/png", cluster: 1,
+				content: "\x89PNG\r\n\x1a\n" + binaryData(5000)},
+			{namespace: 'C', url: "index.html", title: "Metadata", mimetype: "text/html", cluster: 0,
+				content: "<html><head><title>Metadata</title></head><body>metadata</body></html>"},
+		},
+	},
+}
+
+func main() {
+	for _, f := range fixtures {
+		var data, err = f.build()
+		if err != nil {
+			log.Fatalf("%s: %s", f.filename, err)
+		}
+		if err = os.WriteFile(path.Join("testdata", f.filename), data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (f *fixture) build() ([]byte, error) {
+	var declared = append([]fixtureEntry(nil), f.entries...)
+	if len(f.frontArticles) > 0 {
+		declared = append(declared, fixtureEntry{namespace: 'X', url: "listing/titleOrdered/v1",
+			mimetype: "application/octet-stream+zimlisting", cluster: 0})
+	}
+	var entries = append([]fixtureEntry(nil), declared...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].namespace != entries[j].namespace {
+			return entries[i].namespace < entries[j].namespace
+		}
+		return entries[i].url < entries[j].url
+	})
+	var urlIndex = make(map[string]uint32, len(entries))
+	for i := range entries {
+		urlIndex[entries[i].path()] = uint32(i)
+	}
+	if len(f.frontArticles) > 0 {
+		var listing []byte
+		for _, p := range f.frontArticles {
+			listing = binary.LittleEndian.AppendUint32(listing, urlIndex[p])
+		}
+		declared[len(declared)-1].content = string(listing)
+	}
+
+	var titleOrder = make([]uint32, len(entries))
+	for i := range titleOrder {
+		titleOrder[i] = uint32(i)
+	}
+	sort.SliceStable(titleOrder, func(i, j int) bool {
+		var a, b = &entries[titleOrder[i]], &entries[titleOrder[j]]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.sortTitle() < b.sortTitle()
+	})
+
+	var mimetypes []string
+	var mimetypeIndex = make(map[string]uint16)
+	for _, e := range entries {
+		if len(e.redirectTo) == 0 {
+			if _, ok := mimetypeIndex[e.mimetype]; !ok {
+				mimetypeIndex[e.mimetype] = 0
+				mimetypes = append(mimetypes, e.mimetype)
+			}
+		}
+	}
+	sort.Strings(mimetypes)
+	for i, m := range mimetypes {
+		mimetypeIndex[m] = uint16(i)
+	}
+
+	// blobs are numbered in declaration order within their cluster
+	var blobs = make([][][]byte, len(f.clusters))
+	var blobNumber = make(map[string]uint32)
+	for _, e := range declared {
+		if len(e.redirectTo) == 0 {
+			blobNumber[e.path()] = uint32(len(blobs[e.cluster]))
+			blobs[e.cluster] = append(blobs[e.cluster], []byte(e.content))
+		}
+	}
+
+	var buf bytes.Buffer
+	var le = binary.LittleEndian
+	const headerLen = 80
+	buf.Write(make([]byte, headerLen))
+
+	for _, m := range mimetypes {
+		buf.WriteString(m)
+		buf.WriteByte(0)
+	}
+	buf.WriteByte(0)
+
+	var urlPtrPos = uint64(buf.Len())
+	buf.Write(make([]byte, 8*len(entries)))
+	var titlePtrPos = uint64(buf.Len())
+	for _, idx := range titleOrder {
+		buf.Write(le.AppendUint32(nil, idx))
+	}
+
+	var direntPos = make([]uint64, len(entries))
+	for i, e := range entries {
+		direntPos[i] = uint64(buf.Len())
+		if len(e.redirectTo) > 0 {
+			buf.Write(le.AppendUint16(nil, 0xFFFF))
+		} else {
+			buf.Write(le.AppendUint16(nil, mimetypeIndex[e.mimetype]))
+		}
+		buf.WriteByte(uint8(len(e.parameter)))
+		buf.WriteByte(e.namespace)
+		buf.Write(le.AppendUint32(nil, 0)) // revision
+		if len(e.redirectTo) > 0 {
+			buf.Write(le.AppendUint32(nil, urlIndex[e.redirectTo]))
+		} else {
+			buf.Write(le.AppendUint32(nil, uint32(e.cluster)))
+			buf.Write(le.AppendUint32(nil, blobNumber[e.path()]))
+		}
+		buf.WriteString(e.url)
+		buf.WriteByte(0)
+		buf.WriteString(e.title)
+		buf.WriteByte(0)
+		buf.WriteString(e.parameter)
+	}
+	for i, pos := range direntPos {
+		le.PutUint64(buf.Bytes()[urlPtrPos+uint64(8*i):], pos)
+	}
+
+	var clusterPtrPos = uint64(buf.Len())
+	buf.Write(make([]byte, 8*len(f.clusters)))
+	for i, compression := range f.clusters {
+		le.PutUint64(buf.Bytes()[clusterPtrPos+uint64(8*i):], uint64(buf.Len()))
+		var raw, err = clusterData(blobs[i], compression)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(compression)
+		buf.Write(raw)
+	}
+
+	var checksumPos = uint64(buf.Len())
+	var mainPage = ^uint32(0)
+	if len(f.mainPage) > 0 {
+		mainPage = urlIndex[f.mainPage]
+	}
+	var header = buf.Bytes()[:headerLen]
+	le.PutUint32(header[0:], 72173914)
+	le.PutUint16(header[4:], f.majorVersion)
+	le.PutUint16(header[6:], f.minorVersion)
+	copy(header[8:24], f.uuid[:])
+	le.PutUint32(header[24:], uint32(len(entries)))
+	le.PutUint32(header[28:], uint32(len(f.clusters)))
+	le.PutUint64(header[32:], urlPtrPos)
+	le.PutUint64(header[40:], titlePtrPos)
+	le.PutUint64(header[48:], clusterPtrPos)
+	le.PutUint64(header[56:], headerLen)
+	le.PutUint32(header[64:], mainPage)
+	le.PutUint32(header[68:], ^uint32(0))
+	le.PutUint64(header[72:], checksumPos)
+
+	var sum = md5.Sum(buf.Bytes())
+	buf.Write(sum[:])
+	return buf.Bytes(), nil
+}
+
+func clusterData(blobs [][]byte, compression uint8) ([]byte, error) {
+	var raw bytes.Buffer
+	var offset = uint32(4 * (len(blobs) + 1))
+	for _, b := range blobs {
+		raw.Write(binary.LittleEndian.AppendUint32(nil, offset))
+		offset += uint32(len(b))
+	}
+	raw.Write(binary.LittleEndian.AppendUint32(nil, offset))
+	for _, b := range blobs {
+		raw.Write(b)
+	}
+
+	switch compression {
+	case compressionXz:
+		var out bytes.Buffer
+		var w, err = xz.NewWriter(&out)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(raw.Bytes()); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case compressionZstd:
+		var enc, err = zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(raw.Bytes(), nil), nil
+	default:
+		return raw.Bytes(), nil
+	}
+}
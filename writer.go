@@ -0,0 +1,691 @@
+package zim
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WriterOptions configure a Writer. The zero value writes a ZIM 6.1 file
+// with the new namespace scheme and zstd compressed clusters of 2MB.
+type WriterOptions struct {
+	// MajorVersion and MinorVersion of the written file; 0 means version 6.1.
+	// Files with the old namespace scheme should use version 5.0 or 6.0.
+	MajorVersion, MinorVersion uint16
+	// UUID of the written file; nil means a random UUID.
+	UUID UUID
+	// Compression of the clusters with compressible contents like HTML, CSS and JavaScript;
+	// 0 means CompressionZstd. Other contents are stored in uncompressed clusters.
+	Compression Compression
+	// CompressionLevel is 1 (fastest) to 22 (best) for zstd and 1 to 9 for xz; 0 means the default level.
+	// The zstd levels are mapped to the four encoder levels of the zstd package: 1 and 2 are the fastest,
+	// 3 to 5 the default, 6 to 9 better and 10 to 22 the best compression. The xz levels only select
+	// the dictionary size of the xz presets, from 256KB to 64MB, which is limited to the ClusterSize.
+	CompressionLevel int
+	// Workers is the number of clusters compressed concurrently; <= 0 means runtime.GOMAXPROCS(0).
+	// The clusters are written in the same order for any number of workers,
+	// so the same input always results in the same file.
+	Workers int
+	// ClusterSize is the uncompressed size at which a cluster is closed; <= 0 means DefaultClusterSize.
+	ClusterSize int
+	// TempDir is the directory of the temporary file storing the clusters until the file is written;
+	// empty means the default directory for temporary files.
+	TempDir string
+}
+
+// WriterEntry is a Directory Entry with contents, which is added to a Writer.
+type WriterEntry struct {
+	Namespace Namespace
+	URL       string
+	Title     string // empty means the URL is used as title
+	Mimetype  string
+	Content   io.Reader // read by AddEntry; nil means empty contents
+	Parameter []byte    // extra parameter data of at most 255 bytes
+	// FrontArticle lists the entry in the front article listing X/listing/titleOrdered/v1,
+	// which is written for files with the new namespace scheme.
+	FrontArticle bool
+}
+
+// WriterRedirect is a Redirect Entry, which is added to a Writer.
+// The target must be added to the same Writer before it is closed.
+type WriterRedirect struct {
+	Namespace       Namespace
+	URL             string
+	Title           string // empty means the URL is used as title
+	TargetNamespace Namespace
+	TargetURL       string
+	Parameter       []byte // extra parameter data of at most 255 bytes
+}
+
+// writerEntry is a Directory Entry of the written file.
+type writerEntry struct {
+	namespace    Namespace
+	url          string
+	title        string // empty if it's the URL
+	mimetype     string
+	parameter    []byte
+	cluster      uint32 // id of the clusterBuilder until the cluster is closed
+	blob         uint32
+	redirect     bool
+	target       string // path of the redirect target
+	frontArticle bool
+	revision     uint32
+	special      Mimetype // MimetypeLinkTarget or MimetypeDeletedEntry for entries copied from old files
+}
+
+func entryPath(namespace Namespace, url string) string { return string(namespace) + "/" + url }
+
+func (e *writerEntry) sortTitle() string {
+	if len(e.title) > 0 {
+		return e.title
+	}
+	return e.url
+}
+
+// encodedLen is the size of the Directory Entry in the written file.
+func (e *writerEntry) encodedLen() int {
+	var fixed = 16
+	if e.redirect {
+		fixed = 12
+	} else if e.special != 0 {
+		fixed = 8
+	}
+	return fixed + len(e.url) + 1 + len(e.title) + 1 + len(e.parameter)
+}
+
+// clusterJob is a closed cluster, which is compressed by a worker.
+type clusterJob struct {
+	cluster *clusterBuilder
+	encoded []byte
+	err     error
+	done    chan struct{}
+}
+
+// Writer creates a ZIM file, which is readable by Open and by libzim.
+// The contents are grouped into clusters while they are added. Full clusters are compressed
+// by a pool of workers and stored in a temporary file until Close writes the complete ZIM file
+// to the underlying writer. Close must be called to stop the workers, also after errors.
+// A Writer is not safe for concurrent use.
+type Writer struct {
+	w        io.Writer
+	options  WriterOptions
+	temp     *os.File
+	tempSize int64
+
+	encoder *clusterEncoder
+	jobs    chan *clusterJob // nil until the workers are started
+	pending []*clusterJob    // compressed or compressing clusters in cluster order
+	workers sync.WaitGroup
+
+	entries    []*writerEntry
+	paths      map[string]*writerEntry
+	mainPage   string // path of the main page or empty
+	layoutPage string // path of the layout page or empty
+	copied     bool   // the entries are copied from another file, so no entries are generated
+
+	open          map[Compression]*clusterBuilder
+	nextClusterID uint32
+	clusterNumber map[uint32]uint32 // cluster ids of the closed clusters mapped to their cluster number
+	clusterEnds   []int64           // end of each closed cluster in the temporary file
+
+	err    error // the first error, which makes the Writer unusable
+	closed bool
+}
+
+// NewWriter returns a Writer, which writes the ZIM file to w when it's closed.
+func NewWriter(w io.Writer, options WriterOptions) (*Writer, error) {
+	if options.MajorVersion == 0 {
+		options.MajorVersion, options.MinorVersion = 6, 1
+	}
+	if options.MajorVersion != 5 && options.MajorVersion != 6 {
+		return nil, ErrUnsupportedVersion
+	}
+	if options.UUID == nil {
+		options.UUID = make(UUID, uuidLen)
+		if _, err := rand.Read(options.UUID); err != nil {
+			return nil, err
+		}
+	} else if len(options.UUID) != uuidLen {
+		return nil, fmt.Errorf("zim: UUID has %d bytes; want %d", len(options.UUID), uuidLen)
+	}
+	if options.Compression == 0 {
+		options.Compression = CompressionZstd
+	}
+	switch options.Compression {
+	case CompressionNone, CompressionXz, CompressionZstd:
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+	if options.ClusterSize <= 0 {
+		options.ClusterSize = DefaultClusterSize
+	}
+	if options.Workers <= 0 {
+		options.Workers = runtime.GOMAXPROCS(0)
+	}
+	var encoder, encoderErr = newClusterEncoder(options.Compression, options.CompressionLevel, options.Workers,
+		options.ClusterSize)
+	if encoderErr != nil {
+		return nil, encoderErr
+	}
+	var temp, err = os.CreateTemp(options.TempDir, "zim-clusters-*")
+	if err != nil {
+		encoder.close()
+		return nil, err
+	}
+	return &Writer{
+		w:             w,
+		options:       options,
+		temp:          temp,
+		encoder:       encoder,
+		paths:         make(map[string]*writerEntry),
+		open:          make(map[Compression]*clusterBuilder),
+		clusterNumber: make(map[uint32]uint32),
+	}, nil
+}
+
+// newNamespaceScheme reports whether the written file uses the new namespace scheme.
+func (zw *Writer) newNamespaceScheme() bool {
+	return zw.options.MajorVersion >= 6 && zw.options.MinorVersion >= 1
+}
+
+// checkEntry validates the fields shared by Directory Entries and Redirect Entries.
+func checkEntry(namespace Namespace, url, title string, parameter []byte) error {
+	switch {
+	case namespace == 0:
+		return fmt.Errorf("%w: no namespace", ErrInvalidEntry)
+	case len(url) == 0:
+		return fmt.Errorf("%w: empty URL in namespace %c", ErrInvalidEntry, namespace)
+	case strings.IndexByte(url, 0) >= 0 || strings.IndexByte(title, 0) >= 0:
+		return fmt.Errorf("%w: %s contains a null byte", ErrInvalidEntry, entryPath(namespace, url))
+	case len(parameter) > 255:
+		return fmt.Errorf("%w: parameter of %s has more than 255 bytes", ErrInvalidEntry, entryPath(namespace, url))
+	}
+	return nil
+}
+
+// add registers the Directory Entry, if its path isn't used yet.
+func (zw *Writer) add(e *writerEntry) error {
+	if zw.closed {
+		return ErrWriterClosed
+	}
+	if zw.err != nil {
+		return zw.err
+	}
+	if e.title == e.url {
+		e.title = ""
+	}
+	var path = entryPath(e.namespace, e.url)
+	if _, found := zw.paths[path]; found {
+		return fmt.Errorf("%w: %s", ErrDuplicateEntry, path)
+	}
+	zw.paths[path] = e
+	zw.entries = append(zw.entries, e)
+	return nil
+}
+
+// AddEntry reads the contents of the Directory Entry and adds it to a cluster.
+// Every path (namespace and URL) can only be added once.
+func (zw *Writer) AddEntry(entry WriterEntry) error {
+	if err := checkEntry(entry.Namespace, entry.URL, entry.Title, entry.Parameter); err != nil {
+		return err
+	}
+	if len(entry.Mimetype) == 0 || strings.IndexByte(entry.Mimetype, 0) >= 0 {
+		return fmt.Errorf("%w: invalid mimetype of %s", ErrInvalidEntry, entryPath(entry.Namespace, entry.URL))
+	}
+	if zw.closed {
+		return ErrWriterClosed
+	}
+	if _, found := zw.paths[entryPath(entry.Namespace, entry.URL)]; found {
+		return fmt.Errorf("%w: %s", ErrDuplicateEntry, entryPath(entry.Namespace, entry.URL))
+	}
+	var e = &writerEntry{
+		namespace:    entry.Namespace,
+		url:          entry.URL,
+		title:        entry.Title,
+		mimetype:     strings.ToLower(strings.TrimSpace(entry.Mimetype)),
+		parameter:    append([]byte(nil), entry.Parameter...),
+		frontArticle: entry.FrontArticle,
+	}
+	var err = zw.addBlob(e, zw.compressionFor(e.mimetype), func(data *bytes.Buffer) error {
+		if entry.Content == nil {
+			return nil
+		}
+		var _, readErr = data.ReadFrom(entry.Content)
+		return readErr
+	})
+	if err != nil {
+		return err
+	}
+	return zw.add(e)
+}
+
+// compressionFor returns the compression of the clusters of contents with the Mimetype.
+func (zw *Writer) compressionFor(mimetype string) Compression {
+	if compressible(mimetype) {
+		return zw.options.Compression
+	}
+	return CompressionNone
+}
+
+// addBlob adds the contents written by fill to the open cluster with the compression
+// and closes the cluster, when it's full.
+func (zw *Writer) addBlob(e *writerEntry, compression Compression, fill func(*bytes.Buffer) error) error {
+	if zw.err != nil {
+		return zw.err
+	}
+	var cluster = zw.open[compression]
+	if cluster == nil {
+		cluster = &clusterBuilder{id: zw.nextClusterID, compression: compression}
+		zw.nextClusterID++
+		zw.open[compression] = cluster
+	}
+	var blob, err = cluster.addBlob(fill)
+	if err != nil {
+		// the contents couldn't be read, but the Writer is still usable
+		return err
+	}
+	e.cluster, e.blob = cluster.id, blob
+	if cluster.data.Len() >= zw.options.ClusterSize {
+		return zw.closeCluster(compression)
+	}
+	return nil
+}
+
+// closeCluster numbers the open cluster and passes it to the workers.
+// At most two clusters per worker wait to be written, so the memory stays bounded.
+func (zw *Writer) closeCluster(compression Compression) error {
+	var cluster = zw.open[compression]
+	if cluster == nil {
+		return nil
+	}
+	delete(zw.open, compression)
+	var number = uint32(len(zw.clusterNumber))
+	zw.clusterNumber[cluster.id] = number
+	if zw.jobs == nil {
+		zw.startWorkers()
+	}
+	var job = &clusterJob{cluster: cluster, done: make(chan struct{})}
+	zw.pending = append(zw.pending, job)
+	zw.jobs <- job
+	return zw.writeClusters(len(zw.pending) > 2*zw.options.Workers)
+}
+
+// startWorkers starts the workers compressing the clusters.
+func (zw *Writer) startWorkers() {
+	var extendedAllowed = zw.options.MajorVersion >= 6
+	zw.jobs = make(chan *clusterJob, 2*zw.options.Workers+1)
+	for i := 0; i < zw.options.Workers; i++ {
+		zw.workers.Add(1)
+		go func() {
+			defer zw.workers.Done()
+			for job := range zw.jobs {
+				job.encoded, job.err = job.cluster.encode(zw.encoder, extendedAllowed)
+				job.cluster = nil
+				close(job.done)
+			}
+		}()
+	}
+}
+
+// stopWorkers waits until the workers compressed all clusters.
+func (zw *Writer) stopWorkers() {
+	if zw.jobs != nil {
+		close(zw.jobs)
+		zw.workers.Wait()
+		zw.jobs = nil
+	}
+	zw.encoder.close()
+}
+
+// writeClusters appends the compressed clusters to the temporary file in cluster order.
+// If wait is true, it waits for the next cluster, otherwise it only writes the finished ones.
+func (zw *Writer) writeClusters(wait bool) error {
+	for len(zw.pending) > 0 {
+		var job = zw.pending[0]
+		if wait {
+			<-job.done
+			wait = false
+		} else {
+			select {
+			case <-job.done:
+			default:
+				return nil
+			}
+		}
+		zw.pending = zw.pending[1:]
+		var err = job.err
+		if err == nil {
+			_, err = zw.temp.Write(job.encoded)
+		}
+		if err != nil {
+			zw.err = err
+			return err
+		}
+		zw.tempSize += int64(len(job.encoded))
+		zw.clusterEnds = append(zw.clusterEnds, zw.tempSize)
+	}
+	return nil
+}
+
+// flushClusters waits for all clusters and writes them to the temporary file.
+func (zw *Writer) flushClusters() error {
+	for len(zw.pending) > 0 {
+		if err := zw.writeClusters(true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddRedirect adds a Redirect Entry. Its target is looked up when the Writer is closed.
+func (zw *Writer) AddRedirect(redirect WriterRedirect) error {
+	if err := checkEntry(redirect.Namespace, redirect.URL, redirect.Title, redirect.Parameter); err != nil {
+		return err
+	}
+	return zw.add(&writerEntry{
+		namespace: redirect.Namespace,
+		url:       redirect.URL,
+		title:     redirect.Title,
+		parameter: append([]byte(nil), redirect.Parameter...),
+		redirect:  true,
+		target:    entryPath(redirect.TargetNamespace, redirect.TargetURL),
+	})
+}
+
+// SetMetadata adds the metadata value with the key, like "Title" or "Language".
+func (zw *Writer) SetMetadata(key, value string) error {
+	return zw.AddEntry(WriterEntry{Namespace: NamespaceZimMetadata, URL: key, Mimetype: "text/plain",
+		Content: strings.NewReader(value)})
+}
+
+// SetIllustration adds the PNG image of the illustration with the size in pixels and the scale
+// as metadata "Illustration_{size}x{size}@{scale}". Every ZIM file should have an illustration
+// with size 48 and scale 1.
+func (zw *Writer) SetIllustration(size, scale int, png []byte) error {
+	return zw.AddEntry(WriterEntry{Namespace: NamespaceZimMetadata,
+		URL: fmt.Sprintf("Illustration_%dx%d@%d", size, size, scale), Mimetype: "image/png",
+		Content: bytes.NewReader(png)})
+}
+
+// SetMainPage sets the main page, which must be added to the Writer before it is closed.
+// Files with the new namespace scheme also get the redirect W/mainPage to the main page.
+func (zw *Writer) SetMainPage(namespace Namespace, url string) {
+	zw.mainPage = entryPath(namespace, url)
+}
+
+// addGeneratedEntries adds the entries, which are derived from the others:
+// the metadata Counter, the redirect W/mainPage and the front article listing.
+// The listing only gets its contents, when the URL positions are known.
+func (zw *Writer) addGeneratedEntries() (listing *writerEntry, err error) {
+	if _, found := zw.paths[entryPath(NamespaceZimMetadata, "Counter")]; !found {
+		var counter = make(map[string]int)
+		for _, e := range zw.entries {
+			if !e.redirect && e.namespace != NamespaceZimMetadata && e.namespace != NamespaceWellKnown &&
+				e.namespace != NamespaceIndexes {
+				counter[e.mimetype]++
+			}
+		}
+		var mimetypes = make([]string, 0, len(counter))
+		for mimetype := range counter {
+			mimetypes = append(mimetypes, mimetype)
+		}
+		sort.Strings(mimetypes)
+		var pairs = make([]string, len(mimetypes))
+		for i, mimetype := range mimetypes {
+			pairs[i] = mimetype + "=" + strconv.Itoa(counter[mimetype])
+		}
+		if len(pairs) > 0 {
+			if err = zw.SetMetadata("Counter", strings.Join(pairs, ";")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !zw.newNamespaceScheme() {
+		return nil, nil
+	}
+	if _, found := zw.paths[entryPath(NamespaceWellKnown, "mainPage")]; !found && len(zw.mainPage) > 0 {
+		var namespace, url = Namespace(zw.mainPage[0]), zw.mainPage[2:]
+		if err = zw.AddRedirect(WriterRedirect{Namespace: NamespaceWellKnown, URL: "mainPage",
+			TargetNamespace: namespace, TargetURL: url}); err != nil {
+			return nil, err
+		}
+	}
+	var hasFrontArticles bool
+	for _, e := range zw.entries {
+		hasFrontArticles = hasFrontArticles || e.frontArticle
+	}
+	if _, found := zw.paths[entryPath(NamespaceIndexes, frontArticleListingURL)]; found || !hasFrontArticles {
+		return nil, nil
+	}
+	listing = &writerEntry{namespace: NamespaceIndexes, url: frontArticleListingURL,
+		mimetype: "application/octet-stream+zimlisting"}
+	return listing, zw.add(listing)
+}
+
+// Abort stops the Writer without writing anything to the underlying writer and removes the
+// temporary file. It does nothing after Close, so it can be deferred to clean up on errors.
+func (zw *Writer) Abort() {
+	if zw.closed {
+		return
+	}
+	zw.closed = true
+	zw.stopWorkers()
+	zw.temp.Close()
+	os.Remove(zw.temp.Name())
+}
+
+// Close writes the ZIM file to the underlying writer and removes the temporary file.
+// It doesn't close the underlying writer. Close fails if a redirect target or the main page
+// was not added.
+func (zw *Writer) Close() error {
+	if zw.closed {
+		return ErrWriterClosed
+	}
+	defer zw.Abort()
+	if zw.err != nil {
+		return zw.err
+	}
+	var listing *writerEntry
+	var err error
+	if !zw.copied {
+		if listing, err = zw.addGeneratedEntries(); err != nil {
+			return err
+		}
+	}
+
+	// URL order and the positions of the redirect targets
+	var entries = zw.entries
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].namespace != entries[j].namespace {
+			return entries[i].namespace < entries[j].namespace
+		}
+		return entries[i].url < entries[j].url
+	})
+	var urlPosition = make(map[*writerEntry]uint32, len(entries))
+	for i, e := range entries {
+		urlPosition[e] = uint32(i)
+	}
+	var positionOf = func(path string) (uint32, error) {
+		var e, found = zw.paths[path]
+		if !found {
+			return 0, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return urlPosition[e], nil
+	}
+	var redirectTargets = make(map[*writerEntry]uint32)
+	for _, e := range entries {
+		if e.redirect {
+			if redirectTargets[e], err = positionOf(e.target); err != nil {
+				return fmt.Errorf("zim: redirect %s: %w", entryPath(e.namespace, e.url), err)
+			}
+		}
+	}
+	var mainPage, layoutPage = ^uint32(0), ^uint32(0)
+	if len(zw.mainPage) > 0 {
+		if mainPage, err = positionOf(zw.mainPage); err != nil {
+			return fmt.Errorf("zim: main page: %w", err)
+		}
+	}
+	if len(zw.layoutPage) > 0 {
+		if layoutPage, err = positionOf(zw.layoutPage); err != nil {
+			return fmt.Errorf("zim: layout page: %w", err)
+		}
+	}
+
+	// title order; Directory Entries with the same title keep the URL order
+	var titleOrder = make([]uint32, len(entries))
+	for i := range titleOrder {
+		titleOrder[i] = uint32(i)
+	}
+	sort.SliceStable(titleOrder, func(i, j int) bool {
+		var a, b = entries[titleOrder[i]], entries[titleOrder[j]]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.sortTitle() < b.sortTitle()
+	})
+
+	if listing != nil {
+		var data []byte
+		for _, position := range titleOrder {
+			if entries[position].frontArticle {
+				data = binary.LittleEndian.AppendUint32(data, position)
+			}
+		}
+		var fill = func(b *bytes.Buffer) error { _, err := b.Write(data); return err }
+		if err = zw.addBlob(listing, zw.compressionFor(listing.mimetype), fill); err != nil {
+			return err
+		}
+	}
+	for _, compression := range []Compression{zw.options.Compression, CompressionNone} {
+		if err = zw.closeCluster(compression); err != nil {
+			return err
+		}
+	}
+	if err = zw.flushClusters(); err != nil {
+		return err
+	}
+
+	// the Mimetype list is sorted, so the file doesn't depend on the order of AddEntry
+	var mimetypeIndex = make(map[string]uint16)
+	var mimetypes []string
+	for _, e := range entries {
+		if _, found := mimetypeIndex[e.mimetype]; !e.redirect && e.special == 0 && !found {
+			mimetypeIndex[e.mimetype] = 0
+			mimetypes = append(mimetypes, e.mimetype)
+		}
+	}
+	if len(mimetypes) >= int(MimetypeDeletedEntry) {
+		return fmt.Errorf("%w: too many mimetypes", ErrInvalidEntry)
+	}
+	sort.Strings(mimetypes)
+	for i, mimetype := range mimetypes {
+		mimetypeIndex[mimetype] = uint16(i)
+	}
+
+	// the layout of the file: header, mimetype list, URL and title pointerlists,
+	// Directory Entries, cluster pointerlist, clusters and checksum
+	var mimeListSize int64 = 1
+	for _, mimetype := range mimetypes {
+		mimeListSize += int64(len(mimetype)) + 1
+	}
+	var urlPtrPos = int64(headerLen) + mimeListSize
+	var titlePtrPos = urlPtrPos + 8*int64(len(entries))
+	var direntPos = titlePtrPos + 4*int64(len(entries))
+	var clusterPtrPos = direntPos
+	for _, e := range entries {
+		clusterPtrPos += int64(e.encodedLen())
+	}
+	var clustersPos = clusterPtrPos + 8*int64(len(zw.clusterEnds))
+	var checksumPos = clustersPos + zw.tempSize
+
+	var digest = md5.New()
+	var out = bufio.NewWriterSize(io.MultiWriter(zw.w, digest), 1<<20)
+	var le = binary.LittleEndian
+	var header = make([]byte, headerLen)
+	le.PutUint32(header[0:], MagicNumber)
+	le.PutUint16(header[4:], zw.options.MajorVersion)
+	le.PutUint16(header[6:], zw.options.MinorVersion)
+	copy(header[8:24], zw.options.UUID)
+	le.PutUint32(header[24:], uint32(len(entries)))
+	le.PutUint32(header[28:], uint32(len(zw.clusterEnds)))
+	le.PutUint64(header[32:], uint64(urlPtrPos))
+	le.PutUint64(header[40:], uint64(titlePtrPos))
+	le.PutUint64(header[48:], uint64(clusterPtrPos))
+	le.PutUint64(header[56:], headerLen)
+	le.PutUint32(header[64:], mainPage)
+	le.PutUint32(header[68:], layoutPage)
+	le.PutUint64(header[72:], uint64(checksumPos))
+	out.Write(header)
+
+	for _, mimetype := range mimetypes {
+		out.WriteString(mimetype)
+		out.WriteByte(0)
+	}
+	out.WriteByte(0)
+
+	var buf = make([]byte, 0, 16)
+	var position = direntPos
+	for _, e := range entries {
+		out.Write(le.AppendUint64(buf[:0], uint64(position)))
+		position += int64(e.encodedLen())
+	}
+	for _, urlPos := range titleOrder {
+		out.Write(le.AppendUint32(buf[:0], urlPos))
+	}
+
+	for _, e := range entries {
+		buf = buf[:0]
+		switch {
+		case e.redirect:
+			buf = le.AppendUint16(buf, uint16(MimetypeRedirectEntry))
+		case e.special != 0:
+			buf = le.AppendUint16(buf, uint16(e.special))
+		default:
+			buf = le.AppendUint16(buf, mimetypeIndex[e.mimetype])
+		}
+		buf = append(buf, uint8(len(e.parameter)), byte(e.namespace))
+		buf = le.AppendUint32(buf, e.revision)
+		switch {
+		case e.redirect:
+			buf = le.AppendUint32(buf, redirectTargets[e])
+		case e.special != 0:
+		default:
+			buf = le.AppendUint32(buf, zw.clusterNumber[e.cluster])
+			buf = le.AppendUint32(buf, e.blob)
+		}
+		out.Write(buf)
+		out.WriteString(e.url)
+		out.WriteByte(0)
+		out.WriteString(e.title)
+		out.WriteByte(0)
+		out.Write(e.parameter)
+	}
+
+	var clusterStart int64
+	for _, end := range zw.clusterEnds {
+		out.Write(le.AppendUint64(buf[:0], uint64(clustersPos+clusterStart)))
+		clusterStart = end
+	}
+	if _, err = zw.temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, zw.temp); err != nil {
+		return err
+	}
+	if err = out.Flush(); err != nil {
+		return err
+	}
+	_, err = zw.w.Write(digest.Sum(nil))
+	return err
+}
@@ -0,0 +1,158 @@
+package zim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// DefaultClusterSize is the uncompressed size at which the Writer closes a cluster.
+const DefaultClusterSize = 2 << 20
+
+// clusterBuilder collects the blobs of a cluster, which is not written yet.
+type clusterBuilder struct {
+	id          uint32 // the clusters are numbered when they are closed, entries refer to the id until then
+	compression Compression
+	data        bytes.Buffer
+	offsets     []uint64 // start of each blob in data
+}
+
+// addBlob appends the blob read by fill and returns its blob number.
+func (c *clusterBuilder) addBlob(fill func(*bytes.Buffer) error) (uint32, error) {
+	var start = c.data.Len()
+	if err := fill(&c.data); err != nil {
+		c.data.Truncate(start)
+		return 0, err
+	}
+	c.offsets = append(c.offsets, uint64(start))
+	return uint32(len(c.offsets) - 1), nil
+}
+
+// encode returns the cluster information byte followed by the compressed cluster data.
+func (c *clusterBuilder) encode(encoder *clusterEncoder, extendedAllowed bool) ([]byte, error) {
+	var offsetSize = uint64(defaultOffsetSize)
+	var tableSize = uint64(len(c.offsets)+1) * offsetSize
+	var information = uint8(c.compression)
+	if tableSize+uint64(c.data.Len()) > math.MaxUint32 {
+		if !extendedAllowed {
+			return nil, fmt.Errorf("%w: cluster larger than 4GB needs major version 6", ErrInvalidCluster)
+		}
+		offsetSize = extendedOffsetSize
+		tableSize = uint64(len(c.offsets)+1) * offsetSize
+		information |= extendedCluster
+	}
+	var raw = make([]byte, 0, tableSize+uint64(c.data.Len()))
+	var appendOffset = func(offset uint64) {
+		if offsetSize == extendedOffsetSize {
+			raw = binary.LittleEndian.AppendUint64(raw, offset)
+		} else {
+			raw = binary.LittleEndian.AppendUint32(raw, uint32(offset))
+		}
+	}
+	for _, offset := range c.offsets {
+		appendOffset(tableSize + offset)
+	}
+	appendOffset(tableSize + uint64(c.data.Len()))
+	raw = append(raw, c.data.Bytes()...)
+
+	return encoder.compress(c.compression, information, raw)
+}
+
+// xzDictCaps are the dictionary sizes of the xz presets 0 to 9.
+var xzDictCaps = [...]int{256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+// clusterEncoder compresses clusters; it is safe for concurrent use.
+type clusterEncoder struct {
+	zstd     *zstd.Encoder
+	xzConfig xz.WriterConfig
+}
+
+// newClusterEncoder returns an encoder for the compression level, which is
+// 1 to 22 for zstd and 1 to 9 for xz; 0 means the default level, see WriterOptions.
+// concurrency is the number of clusters compressed at once. The xz dictionary
+// is not larger than the cluster size, since a larger dictionary only needs more memory.
+func newClusterEncoder(compression Compression, level, concurrency, clusterSize int) (*clusterEncoder, error) {
+	var encoder = new(clusterEncoder)
+	switch compression {
+	case CompressionZstd:
+		var zstdLevel = zstd.SpeedDefault
+		if level != 0 {
+			if level < 1 || level > 22 {
+				return nil, fmt.Errorf("zim: zstd compression level %d not in range 1 to 22", level)
+			}
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		var err error
+		encoder.zstd, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(concurrency))
+		if err != nil {
+			return nil, err
+		}
+	case CompressionXz:
+		if level < 0 || level > 9 {
+			return nil, fmt.Errorf("zim: xz compression level %d not in range 1 to 9", level)
+		}
+		encoder.xzConfig.DictCap = xzDictCaps[6]
+		if level != 0 {
+			encoder.xzConfig.DictCap = xzDictCaps[level]
+		}
+		if encoder.xzConfig.DictCap > clusterSize {
+			encoder.xzConfig.DictCap = max(clusterSize, xzDictCaps[0])
+		}
+		if err := encoder.xzConfig.Verify(); err != nil {
+			return nil, err
+		}
+	}
+	return encoder, nil
+}
+
+// compress returns the cluster information byte followed by the compressed data.
+func (e *clusterEncoder) compress(compression Compression, information uint8, raw []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte(information)
+	switch compression {
+	case CompressionNone:
+		out.Write(raw)
+	case CompressionXz:
+		var w, err = e.xzConfig.NewWriter(&out)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(raw); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		return e.zstd.EncodeAll(raw, out.Bytes()), nil
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+	return out.Bytes(), nil
+}
+
+// close releases the resources of the encoder.
+func (e *clusterEncoder) close() {
+	if e.zstd != nil {
+		e.zstd.Close()
+	}
+}
+
+// compressible reports whether contents of the Mimetype are worth compressing.
+// Images, videos, audio and archives are mostly compressed already.
+func compressible(mimetype string) bool {
+	mimetype = strings.ToLower(mimetype)
+	switch {
+	case strings.HasPrefix(mimetype, "text/"):
+		return true
+	case strings.Contains(mimetype, "javascript"), strings.Contains(mimetype, "json"),
+		strings.Contains(mimetype, "xml"):
+		return true
+	}
+	return false
+}
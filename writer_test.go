@@ -0,0 +1,325 @@
+package zim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+type writerTestEntry struct {
+	namespace Namespace
+	url       string
+	title     string
+	mimetype  string
+	content   string
+	parameter string
+	front     bool
+}
+
+var writerTestEntries = []writerTestEntry{
+	{NamespaceContent, "index.html", "Welcome", "text/html", "<html><body>" + strings.Repeat("<p>welcome</p>", 50) + "</body></html>", "", true},
+	{NamespaceContent, "Zebra", "Zebra", "text/html", "<html><body>" + strings.Repeat("<p>zebra</p>", 80) + "</body></html>", "\x00\x01", true},
+	{NamespaceContent, "Ant", "", "text/html", "<html><body>ant</body></html>", "", true},
+	{NamespaceContent, "assets/style.css", "", "text/css", strings.Repeat("p { margin: 0; }\n", 40), "", false},
+	{NamespaceContent, "assets/image.png", "", "image/png", "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00\x7f\xff", 500), "", false},
+	{NamespaceContent, "empty.txt", "Empty", "text/plain", "", "", false},
+	{NamespaceZimMetadata, "Title", "", "text/plain", "Writer test", "", false},
+	{NamespaceZimMetadata, "Language", "", "text/plain", "eng", "", false},
+}
+
+func writeTestFile(t *testing.T, options WriterOptions, entries []writerTestEntry) []byte {
+	var buf bytes.Buffer
+	var zw, err = NewWriter(&buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err = zw.AddEntry(WriterEntry{Namespace: e.namespace, URL: e.url, Title: e.title, Mimetype: e.mimetype,
+			Content: strings.NewReader(e.content), Parameter: []byte(e.parameter), FrontArticle: e.front}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.AddRedirect(WriterRedirect{Namespace: entries[0].namespace, URL: "Start", Title: "Start",
+		TargetNamespace: entries[0].namespace, TargetURL: entries[0].url, Parameter: []byte("r")}); err != nil {
+		t.Fatal(err)
+	}
+	zw.SetMainPage(entries[0].namespace, entries[0].url)
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	var uuid = UUID(bytes.Repeat([]byte{0xab}, uuidLen))
+	var data = writeTestFile(t, WriterOptions{UUID: uuid, ClusterSize: 1024}, writerTestEntries)
+	if again := writeTestFile(t, WriterOptions{UUID: uuid, ClusterSize: 1024}, writerTestEntries); !bytes.Equal(data, again) {
+		t.Error("writing the same entries twice returned different files")
+	}
+
+	var zw, openErr = OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if err := zw.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+	if !zw.HasNewNamespaceScheme() || zw.UUID().String() != uuid.String() || zw.Title() != "Writer test" {
+		t.Errorf("header or metadata of the written file is wrong: UUID %s, title `%s`", zw.UUID(), zw.Title())
+	}
+	if counter := zw.Counter(); fmt.Sprint(counter) != "map[image/png:1 text/css:1 text/html:3 text/plain:1]" {
+		t.Errorf("zw.Counter() was %v", counter)
+	}
+	if zw.ClusterCount() < 3 {
+		t.Errorf("zw.ClusterCount() was %d; want at least 3 clusters of 1KB", zw.ClusterCount())
+	}
+
+	for _, e := range writerTestEntries {
+		var entry, _, err = zw.EntryWithURL(e.namespace, []byte(e.url))
+		if err != nil {
+			t.Errorf("zw.EntryWithURL(%c, %s) returned `%v`", e.namespace, e.url, err)
+			continue
+		}
+		var reader, _, readerErr = zw.BlobReader(&entry)
+		if readerErr != nil {
+			t.Fatal(readerErr)
+		}
+		var content, readErr = io.ReadAll(reader)
+		if readErr != nil || string(content) != e.content {
+			t.Errorf("contents of %s were %d bytes, `%v`; want %d bytes", entry.String(), len(content), readErr, len(e.content))
+		}
+		var title = e.title
+		if len(title) == 0 {
+			title = e.url
+		}
+		if string(entry.Title()) != title || string(entry.Parameter()) != e.parameter {
+			t.Errorf("%s has title `%s` and parameter %q", entry.String(), entry.Title(), entry.Parameter())
+		}
+		if mimetype, _ := zw.MimetypeOf(&entry); mimetype != e.mimetype {
+			t.Errorf("%s has mimetype `%s`; want `%s`", entry.String(), mimetype, e.mimetype)
+		}
+		var cluster, clusterErr = zw.ClusterAt(entry.ClusterNumber())
+		if clusterErr != nil {
+			t.Fatal(clusterErr)
+		}
+		if cluster.WasCompressed() != compressible(e.mimetype) {
+			t.Errorf("cluster of %s was compressed: %t", entry.String(), cluster.WasCompressed())
+		}
+	}
+
+	var mainPage, mainPageErr = zw.MainPage()
+	if mainPageErr != nil || string(mainPage.URL()) != "index.html" {
+		t.Errorf("zw.MainPage() returned %s, `%v`", mainPage.String(), mainPageErr)
+	}
+	var start, _, startErr = zw.EntryWithURL(NamespaceContent, []byte("Start"))
+	if startErr != nil || !start.IsRedirect() || string(start.Parameter()) != "r" {
+		t.Fatalf("redirect Start was %s, `%v`", start.String(), startErr)
+	}
+	if target, err := zw.FollowRedirect(&start); err != nil || string(target.URL()) != "index.html" {
+		t.Errorf("zw.FollowRedirect(Start) returned %s, `%v`", target.String(), err)
+	}
+	if titles := frontArticleTitles(t, zw); strings.Join(titles, ",") != "Ant,Welcome,Zebra" {
+		t.Errorf("front articles were %v; want [Ant Welcome Zebra]", titles)
+	}
+
+	var previous []byte
+	for entry, err := range zw.EntriesByTitle(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var key = append([]byte{byte(entry.Namespace())}, entry.Title()...)
+		if bytes.Compare(previous, key) > 0 {
+			t.Errorf("title pointerlist is not sorted: `%s` before `%s`", previous, key)
+		}
+		previous = key
+	}
+}
+
+func TestWriterOldNamespaceScheme(t *testing.T) {
+	var entries = []writerTestEntry{
+		{NamespaceArticles, "index.html", "Index", "text/html", "<html><body>" + strings.Repeat("<p>xz</p>", 100) + "</body></html>", "", true},
+		{NamespaceImagesFiles, "image.png", "", "image/png", "\x89PNG\r\n\x1a\n", "", false},
+		{NamespaceZimMetadata, "Title", "", "text/plain", "Old scheme", "", false},
+	}
+	var data = writeTestFile(t, WriterOptions{MajorVersion: 5, Compression: CompressionXz}, entries)
+	var zw, openErr = OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	if err := zw.ValidateChecksum(); err != nil {
+		t.Error(err)
+	}
+	if major, minor := zw.Version(); major != 5 || minor != 0 || zw.HasNewNamespaceScheme() {
+		t.Errorf("zw.Version() was %d.%d", major, minor)
+	}
+	// the old namespace scheme has no W and X namespaces
+	for _, namespace := range []Namespace{NamespaceWellKnown, NamespaceIndexes} {
+		if _, _, err := zw.NamespaceRange(namespace); !errors.Is(err, ErrNotFound) {
+			t.Errorf("written file has entries in namespace %c", namespace)
+		}
+	}
+	var entry, _, err = zw.EntryWithPath("index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cluster, clusterErr = zw.ClusterAt(entry.ClusterNumber())
+	if clusterErr != nil || !cluster.WasCompressed() {
+		t.Errorf("cluster of index.html was not compressed: `%v`", clusterErr)
+	}
+	if mainPage, err := zw.MainPage(); err != nil || string(mainPage.URL()) != "index.html" {
+		t.Errorf("zw.MainPage() returned %s, `%v`", mainPage.String(), err)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	var zw, err = NewWriter(io.Discard, WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid = WriterEntry{Namespace: NamespaceContent, URL: "a", Mimetype: "text/plain"}
+	if err = zw.AddEntry(valid); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.AddEntry(valid); !errors.Is(err, ErrDuplicateEntry) {
+		t.Errorf("adding an entry twice returned `%v`; want `%s`", err, ErrDuplicateEntry)
+	}
+	if err = zw.AddRedirect(WriterRedirect{Namespace: NamespaceContent, URL: "a"}); !errors.Is(err, ErrDuplicateEntry) {
+		t.Errorf("adding a redirect with the URL of an entry returned `%v`; want `%s`", err, ErrDuplicateEntry)
+	}
+	for _, invalid := range []WriterEntry{
+		{Namespace: NamespaceContent, Mimetype: "text/plain"},
+		{URL: "b", Mimetype: "text/plain"},
+		{Namespace: NamespaceContent, URL: "b"},
+		{Namespace: NamespaceContent, URL: "b\x00", Mimetype: "text/plain"},
+		{Namespace: NamespaceContent, URL: "b", Mimetype: "text/plain", Parameter: make([]byte, 256)},
+	} {
+		if err = zw.AddEntry(invalid); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("zw.AddEntry(%+v) returned `%v`; want `%s`", invalid, err, ErrInvalidEntry)
+		}
+	}
+	if err = zw.AddRedirect(WriterRedirect{Namespace: NamespaceContent, URL: "c",
+		TargetNamespace: NamespaceContent, TargetURL: "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("zw.Close() with a missing redirect target returned `%v`; want `%s`", err, ErrNotFound)
+	}
+	if err = zw.AddEntry(WriterEntry{Namespace: NamespaceContent, URL: "d", Mimetype: "text/plain"}); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("zw.AddEntry() after Close returned `%v`; want `%s`", err, ErrWriterClosed)
+	}
+	if err = zw.Close(); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("second zw.Close() returned `%v`; want `%s`", err, ErrWriterClosed)
+	}
+
+	// an aborted Writer writes nothing and can't be closed anymore
+	var buf bytes.Buffer
+	if zw, err = NewWriter(&buf, WriterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.AddEntry(valid); err != nil {
+		t.Fatal(err)
+	}
+	zw.Abort()
+	if _, statErr := os.Stat(zw.temp.Name()); !os.IsNotExist(statErr) {
+		t.Errorf("temporary file %s of the aborted Writer exists: `%v`", zw.temp.Name(), statErr)
+	}
+	if err = zw.Close(); !errors.Is(err, ErrWriterClosed) || buf.Len() > 0 {
+		t.Errorf("zw.Close() after Abort returned `%v` and wrote %d bytes; want `%s`", err, buf.Len(), ErrWriterClosed)
+	}
+	zw.Abort()
+
+	if _, err = NewWriter(io.Discard, WriterOptions{Compression: Compression(2)}); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Errorf("NewWriter() with zlib compression returned `%v`; want `%s`", err, ErrUnsupportedCompression)
+	}
+	if _, err = NewWriter(io.Discard, WriterOptions{MajorVersion: 7}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("NewWriter() with version 7 returned `%v`; want `%s`", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestWriterParallelCompression(t *testing.T) {
+	var entries []writerTestEntry
+	for i := 0; i < 300; i++ {
+		entries = append(entries, writerTestEntry{NamespaceContent, fmt.Sprintf("page%03d.html", i), fmt.Sprintf("Page %d", i),
+			"text/html", fmt.Sprintf("<html><body>%s</body></html>", strings.Repeat(fmt.Sprintf("<p>page %d</p>", i), 20+i%50)), "", i%3 == 0})
+		if i%10 == 0 {
+			entries = append(entries, writerTestEntry{NamespaceContent, fmt.Sprintf("image%03d.png", i), "",
+				"image/png", "\x89PNG\r\n\x1a\n" + strings.Repeat(string(rune('a'+i%26)), 300+i), "", false})
+		}
+	}
+	var uuid = UUID(bytes.Repeat([]byte{0x01}, uuidLen))
+	for _, compression := range []Compression{CompressionZstd, CompressionXz} {
+		var sizes = make(map[int]int)
+		for _, level := range []int{0, 1, 9} {
+			var sequential = writeTestFile(t, WriterOptions{UUID: uuid, Compression: compression, CompressionLevel: level,
+				ClusterSize: 4096, Workers: 1}, entries)
+			var parallel = writeTestFile(t, WriterOptions{UUID: uuid, Compression: compression, CompressionLevel: level,
+				ClusterSize: 4096, Workers: 8}, entries)
+			if !bytes.Equal(sequential, parallel) {
+				t.Errorf("compression %d level %d: 1 and 8 workers wrote different files", compression, level)
+			}
+			sizes[level] = len(parallel)
+
+			var zw, err = OpenReaderAt(bytes.NewReader(parallel), int64(len(parallel)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if zw.ClusterCount() < 20 {
+				t.Errorf("compression %d level %d: %d clusters; want at least 20", compression, level, zw.ClusterCount())
+			}
+			for _, e := range entries {
+				var entry, _, entryErr = zw.EntryWithURL(e.namespace, []byte(e.url))
+				if entryErr != nil {
+					t.Fatal(entryErr)
+				}
+				var reader, _, readerErr = zw.BlobReader(&entry)
+				if readerErr != nil {
+					t.Fatal(readerErr)
+				}
+				if content, readErr := io.ReadAll(reader); readErr != nil || string(content) != e.content {
+					t.Fatalf("compression %d level %d: contents of %s differ: `%v`", compression, level, e.url, readErr)
+				}
+			}
+		}
+		if sizes[9] > sizes[1] {
+			t.Errorf("compression %d: level 9 wrote %d bytes, level 1 %d bytes", compression, sizes[9], sizes[1])
+		}
+	}
+
+	for _, options := range []WriterOptions{
+		{Compression: CompressionZstd, CompressionLevel: 23},
+		{Compression: CompressionXz, CompressionLevel: 10},
+		{Compression: CompressionXz, CompressionLevel: -1},
+	} {
+		if _, err := NewWriter(io.Discard, options); err == nil {
+			t.Errorf("NewWriter(%+v) returned no error", options)
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	var page = []byte("<html><body>" + strings.Repeat("<p>A paragraph of a benchmark page.</p>", 2000) + "</body></html>")
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(200 * len(page)))
+			for i := 0; i < b.N; i++ {
+				var zw, err = NewWriter(io.Discard, WriterOptions{Workers: workers, ClusterSize: 1 << 20})
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < 200; j++ {
+					if err = zw.AddEntry(WriterEntry{Namespace: NamespaceContent, URL: fmt.Sprintf("page%d", j),
+						Mimetype: "text/html", Content: bytes.NewReader(page)}); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err = zw.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}